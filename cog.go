@@ -0,0 +1,143 @@
+package tiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Layout is the arrangement of IFDs and image data in a file written by an Encoder.
+type Layout int
+
+const (
+	// LayoutDefault writes the IFD of each image before its image data,
+	// or after the image data of all images with NewEncoderAt.
+	LayoutDefault Layout = iota
+
+	// LayoutCOG writes a Cloud Optimized GeoTIFF. The header is followed by the GDAL structural metadata
+	// (the "ghost area") and the IFDs of all images, then the tiles of each image in row-major order,
+	// from the smallest image to the largest one.
+	//
+	// Images must be tiled, and are encoded in the order of their IFDs, i.e. the full resolution image
+	// before its overviews. Compressed tiles are kept in memory until the Encoder is closed.
+	LayoutCOG
+)
+
+// SetLayout sets the layout of the file. It must be called before encoding the first image.
+func (enc *Encoder) SetLayout(layout Layout) {
+	enc.layout = layout
+}
+
+// cogGhostArea returns the GDAL structural metadata written after the header of a COG.
+func cogGhostArea() []byte {
+	const metadata = "LAYOUT=IFDS_BEFORE_DATA\nBLOCK_ORDER=ROW_MAJOR\nKNOWN_INCOMPATIBLE_EDITION=NO\n"
+	return []byte(fmt.Sprintf("GDAL_STRUCTURAL_METADATA_SIZE=%06d bytes\n%s", len(metadata), metadata))
+}
+
+// writeCOG writes all images kept in memory with LayoutCOG.
+func (enc *Encoder) writeCOG() error {
+	images := enc.images
+	if len(images) == 0 {
+		return nil
+	}
+	header := enc.Header.encodeBytes()
+	ghost := cogGhostArea()
+
+	//
+	// Find offsets of IFDs, whose sizes do not depend on the offsets they contain
+	//
+	ifdOffsets := make([]int64, len(images))
+	offset := wordAlign(int64(len(header) + len(ghost)))
+	for i, im := range images {
+		ifdOffsets[i] = offset
+		buf, err := im.EncodeTags(offset)
+		if err != nil {
+			return err
+		}
+		offset = wordAlign(offset + int64(len(buf)))
+	}
+
+	//
+	// Find offsets of tiles, from the smallest image to the largest one
+	//
+	order := make([]int, len(images))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		wa, ha := images[order[a]].WidthHeight()
+		wb, hb := images[order[b]].WidthHeight()
+		return wa*ha < wb*hb
+	})
+	tiled := &segmentLayout{tiled: true}
+	for _, i := range order {
+		im := images[i]
+		offsets := make([]uint64, len(im.segments))
+		byteCounts := make([]uint64, len(im.segments))
+		for j, seg := range im.segments {
+			if seg == nil {
+				// Sparse tile
+				continue
+			}
+			offsets[j] = uint64(offset)
+			byteCounts[j] = uint64(len(seg))
+			offset += int64(len(seg))
+		}
+		if err := im.setSegmentTags(tiled, offsets, byteCounts); err != nil {
+			return err
+		}
+	}
+
+	//
+	// Write header, ghost area, IFDs and tiles
+	//
+	enc.Header.OffsetFirstIFD = ifdOffsets[0]
+	if _, err := enc.write(enc.Header.encodeBytes()); err != nil {
+		return err
+	}
+	if _, err := enc.write(ghost); err != nil {
+		return err
+	}
+	for i, im := range images {
+		if err := enc.writePadding(ifdOffsets[i]); err != nil {
+			return err
+		}
+		if i < len(images)-1 {
+			im.OffsetNext = ifdOffsets[i+1]
+		}
+		buf, err := im.EncodeTags(ifdOffsets[i])
+		if err != nil {
+			return err
+		}
+		if _, err := enc.write(buf); err != nil {
+			return err
+		}
+	}
+	if err := enc.writePadding(wordAlign(enc.offset)); err != nil {
+		return err
+	}
+	for _, i := range order {
+		im := images[i]
+		for _, seg := range im.segments {
+			if _, err := enc.write(seg); err != nil {
+				return err
+			}
+		}
+		im.segments = nil
+	}
+	enc.images = nil
+	return nil
+}
+
+// wordAlign rounds offset up to a word boundary, where IFDs begin.
+func wordAlign(offset int64) int64 {
+	return offset + offset%2
+}
+
+// writePadding writes zeros up to offset.
+func (enc *Encoder) writePadding(offset int64) error {
+	if offset <= enc.offset {
+		return nil
+	}
+	_, err := enc.write(make([]byte, offset-enc.offset))
+	return err
+}
@@ -0,0 +1,93 @@
+package tiff_test
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestEncode_COG(t *testing.T) {
+	sizes := [][2]int{{100, 70}, {50, 35}, {25, 18}}
+	data := make([][]uint8, len(sizes))
+	for i, size := range sizes {
+		data[i] = make([]uint8, size[0]*size[1]*3)
+		for j := range data[i] {
+			data[i][j] = uint8(j/3 + i)
+		}
+	}
+
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	enc.SetLayout(tiff.LayoutCOG)
+	enc.SetVersion(tiff.VersionBigTIFF)
+	for i, size := range sizes {
+		im := enc.NewImage()
+		im.SetWidthHeight(size[0], size[1])
+		im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+		im.SetCompression(tiff.CompressionDeflate)
+		im.SetTileWidthHeight(16, 16)
+		if i > 0 {
+			im.SetTag(tiff.TagNewSubfileType, tiff.TagTypeLong, uint32(tiff.NewFiletypeReducedImage))
+		}
+		if err := im.EncodeImage(data[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	im := enc.NewImage()
+	im.SetWidthHeight(10, 10)
+	im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+	if err := im.EncodeImage(make([]uint8, 10*10*3)); err == nil {
+		t.Error("expecting error for a stripped image in COG layout")
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(w.buf[16:], []byte("GDAL_STRUCTURAL_METADATA_SIZE=")) {
+		t.Fatalf("missing GDAL structural metadata: %q", w.buf[16:64])
+	}
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ims) != len(sizes) {
+		t.Fatalf("found %d images, want %d", len(ims), len(sizes))
+	}
+
+	// IFDs are before all tiles, and tiles of smaller images are before tiles of larger images
+	var lastIFD int64
+	for _, im := range ims {
+		if im.Offset > lastIFD {
+			lastIFD = im.Offset
+		}
+	}
+	nextImageEnd := uint(len(w.buf))
+	for i, im := range ims {
+		offsets, _ := im.Tag[tiff.TagTileOffsets].UintSlice()
+		if !sort.SliceIsSorted(offsets, func(a, b int) bool { return offsets[a] < offsets[b] }) {
+			t.Errorf("image %d: tiles are not in row-major order", i)
+		}
+		if int64(offsets[0]) <= lastIFD {
+			t.Errorf("image %d: tile at offset %d before IFD at offset %d", i, offsets[0], lastIFD)
+		}
+		if offsets[len(offsets)-1] >= nextImageEnd {
+			t.Errorf("image %d: tiles are not before tiles of the larger image", i)
+		}
+		nextImageEnd = offsets[0]
+
+		buf := make([]uint8, len(data[i]))
+		if err := im.DecodeImage(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf, data[i]) {
+			t.Fatalf("image %d: data inconsistent with source", i)
+		}
+	}
+}
@@ -2,12 +2,12 @@ package tiff
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
-	"unsafe"
+	"sync"
+	"sync/atomic"
 
 	// Compression formats
 	"image"      // JPEG
@@ -100,19 +100,27 @@ func (it *Iter) Err() error {
 type Decoder struct {
 	Header *Header
 
-	r io.ReaderAt
+	r           io.ReaderAt
+	maxDataSize int64 // Maximum size of the data of a tag, or 0 if unknown
 }
 
 // NewDecoder returns a TIFF decoder.
+//
+// If r has a Size method, such as *bytes.Reader and *io.SectionReader, tags with more data than r
+// are rejected before allocating their data.
 func NewDecoder(r io.ReaderAt) (*Decoder, error) {
 	header, err := decodeHeader(r)
 	if err != nil {
 		return nil, err
 	}
-	return &Decoder{
+	d := &Decoder{
 		Header: header,
 		r:      r,
-	}, nil
+	}
+	if sized, ok := r.(interface{ Size() int64 }); ok {
+		d.maxDataSize = sized.Size()
+	}
+	return d, nil
 }
 
 // Iter returns an Image iterator.
@@ -176,6 +184,8 @@ func (d *Decoder) DecodeIFD(offset int64) (im *Image, err error) {
 	im = &Image{
 		Offset: offset,
 		Tag:    make(map[TagID]*Tag),
+		Header: d.Header,
+		rd:     d.r,
 	}
 
 	var bytesOfNumTags int
@@ -210,7 +220,7 @@ func (d *Decoder) DecodeIFD(offset int64) (im *Image, err error) {
 	}
 
 	// Read IFD Entries
-	var lastTagID int
+	lastTagID := -1 // Tag 0 is valid, e.g. GPSVersionID
 
 	for i := 0; i < numEntries; i++ {
 		offsetEntry := offset + int64(bytesOfNumTags) + int64(i)*int64(bytesOfEntry)
@@ -237,6 +247,9 @@ func (d *Decoder) DecodeIFD(offset int64) (im *Image, err error) {
 			t.Count = int(count)
 		}
 
+		if size := int64(t.Type.Size()); d.maxDataSize > 0 && size > 0 && int64(t.Count) > d.maxDataSize/size {
+			return nil, fmt.Errorf("invalid IFD: data of tag %d is larger than %d bytes", t.ID, d.maxDataSize)
+		}
 		bytesOfData := t.Type.Size() * t.Count
 		t.Data = make([]byte, bytesOfData)
 		if bytesOfData > byteOfOffset {
@@ -565,205 +578,277 @@ func (im *Image) decodeJPEGSegment(raw *io.SectionReader) (r io.ReadCloser, err
 	return ioutil.NopCloser(bytes.NewReader(buf)), nil
 }
 
+// DecodeOptions are options for decoding image data.
+// A nil *DecodeOptions is equivalent to the zero value.
+type DecodeOptions struct {
+	// Workers is the number of strips or tiles decoded concurrently.
+	// If Workers is less than 2, segments are decoded sequentially by the calling goroutine.
+	Workers int
+
+	// FillValue is the value of all samples of sparse strips or tiles, which have zero offset and byte count.
+	// If FillValue is nil, the value of TagGDALNoData is used if present, otherwise sparse segments are filled with 0.
+	// Decoding a sparse segment fails if the value is not an integer within the range of samples.
+	FillValue *float64
+}
+
+func (opts *DecodeOptions) workers() int {
+	if opts == nil || opts.Workers < 1 {
+		return 1
+	}
+	return opts.Workers
+}
+
+// fillSample returns a sample of sparse segments encoded in the byte order of the image.
+//
+// It returns an error if the fill value is not an integer within the range of samples.
+func (im *Image) fillSample(l *segmentLayout, opts *DecodeOptions) ([]byte, error) {
+	var value float64
+	if opts != nil && opts.FillValue != nil {
+		value = *opts.FillValue
+	} else if noData, ok := im.GDALNoData(); ok {
+		value = noData
+	}
+	max := float64(uint(1)<<(8*uint(l.bytesPerSample)) - 1)
+	if !(value >= 0 && value <= max) || value != math.Trunc(value) {
+		// Negative, NaN, too large or fractional
+		return nil, fmt.Errorf("fill value %v cannot be represented in %d-bit unsigned samples", value, 8*l.bytesPerSample)
+	}
+	sample := make([]byte, l.bytesPerSample)
+	switch l.bytesPerSample {
+	case 1:
+		sample[0] = uint8(value)
+	case 2:
+		im.Header.ByteOrder.PutUint16(sample, uint16(value))
+	}
+	return sample, nil
+}
+
+// SparseSegments returns the indices of strips or tiles with zero offset and byte count,
+// which are not stored in the file. Tiles are indexed in row-major order.
+//
+// Sparse segments are decoded as if all their samples had the fill value of DecodeOptions.
+func (im *Image) SparseSegments() []int {
+	offsetTagID, byteCountTagID := TagStripOffsets, TagStripByteCounts
+	if im.NumStrips() == 0 {
+		offsetTagID, byteCountTagID = TagTileOffsets, TagTileByteCounts
+	}
+	offsets, _ := im.Tag[offsetTagID].UintSlice()
+	byteCounts, _ := im.Tag[byteCountTagID].UintSlice()
+	var sparse []int
+	for i := 0; i < len(offsets) && i < len(byteCounts); i++ {
+		if offsets[i] == 0 && byteCounts[i] == 0 {
+			sparse = append(sparse, i)
+		}
+	}
+	return sparse
+}
+
 // DecodeImage decodes image data and copy data to buffer.
+//
+// The buffer must be []uint8 for DataType Uint8 and []uint16 for DataType Uint16,
+// and holds width*height*SamplesPerPixel() samples.
 func (im *Image) DecodeImage(buffer interface{}) error {
-	//
-	// Find width and height
-	//
+	return im.DecodeImageWithOptions(buffer, nil)
+}
+
+// DecodeImageWithOptions is like DecodeImage, with options such as the number of concurrent workers.
+func (im *Image) DecodeImageWithOptions(buffer interface{}, opts *DecodeOptions) error {
 	width, height := im.WidthHeight()
-	if width == 0 {
-		return fmt.Errorf("invalid image width")
+	return im.DecodeRegionWithOptions(image.Rect(0, 0, width, height), buffer, opts)
+}
+
+// DecodeRegion decodes image data within rect and copy data to buffer.
+//
+// Only the strips or tiles intersecting rect are read and decompressed.
+// The buffer is of the same type as required by DecodeImage, and holds rect.Dx()*rect.Dy()*SamplesPerPixel()
+// samples in row-major order. The rectangle must be within the bounds of the image.
+func (im *Image) DecodeRegion(rect image.Rectangle, buffer interface{}) error {
+	return im.DecodeRegionWithOptions(rect, buffer, nil)
+}
+
+// DecodeRegionWithOptions is like DecodeRegion, with options such as the number of concurrent workers.
+//
+// When segments are decoded concurrently, each of them is copied to a disjoint area of buffer.
+// If more than one segment fails, the error of the segment with the smallest index is returned,
+// which is the same error as returned by sequential decoding.
+func (im *Image) DecodeRegionWithOptions(rect image.Rectangle, buffer interface{}, opts *DecodeOptions) error {
+	l, err := im.decodeLayout()
+	if err != nil {
+		return err
+	}
+	if rect.Empty() || !rect.In(l.bounds()) {
+		return fmt.Errorf("region %v is out of image bounds %v", rect, l.bounds())
 	}
-	if height == 0 {
-		return fmt.Errorf("invalid image height")
+	if err := l.checkBuffer(buffer, rect.Dx()*rect.Dy()); err != nil {
+		return err
 	}
-	pixelCount := width * height
 
-	//
-	// Find bitDepth and samplePerPixel
-	//
-	samplePerPixel := im.SamplesPerPixel()
-	bitDepth := im.BitDepth()
-	switch bitDepth {
-	case 1:
-		return UnsupportedError("1-bit image is not yet supported")
-	case 8:
-		if buf, ok := buffer.([]uint8); ok {
-			if len(buf) != pixelCount*samplePerPixel {
-				return fmt.Errorf("wrong buffer size")
-			}
-		} else {
-			return fmt.Errorf("expecting []uint8")
-		}
-	case 16:
-		if buf, ok := buffer.([]uint16); ok {
-			if len(buf) != pixelCount*samplePerPixel {
-				return fmt.Errorf("wrong buffer size")
-			}
-		} else {
-			return fmt.Errorf("expecting []uint16")
-		}
-	default:
-		bitsPerSample := im.BitsPerSample()
-		return UnsupportedError(fmt.Sprintf("BitsPerSample of %v", bitsPerSample))
-	}
-
-	//
-	// PlanarConfig
-	//
-	planarConfig := im.PlanarConfig()
-	if planarConfig != PlanarConfigContig {
-		return UnsupportedError(fmt.Sprintf("PlanarConfiguration of %v", planarConfig))
-	}
-
-	//
-	// Strip mode
-	//
-	numStrips := im.NumStrips()
-	if numStrips > 0 {
-		// Find expected number of strips
-		rowsPerStrip, ok := im.Tag[TagRowsPerStrip].Uint()
-		if !ok {
-			return FormatError("invalid RowsPerStrip")
-		}
-		ny := (int)(math.Ceil((float64)(height) / (float64)(rowsPerStrip)))
-		if ny != numStrips {
-			return FormatError(fmt.Sprintf("expecting %d strips, found %d strips", ny, numStrips))
-		}
+	// Range of segments intersecting with rect
+	ix0 := rect.Min.X / l.segmentWidth
+	ix1 := (rect.Max.X - 1) / l.segmentWidth
+	iy0 := rect.Min.Y / l.segmentHeight
+	iy1 := (rect.Max.Y - 1) / l.segmentHeight
+	nx := ix1 - ix0 + 1
+	numSegments := nx * (iy1 - iy0 + 1)
 
-		// Process strips
-		samplesPerRow := width * samplePerPixel
-		rowBuf := make([]byte, samplesPerRow*bitDepth/8)
-		numRows := int(rowsPerStrip)
-		destOffset := 0
-		for iy := 0; iy < ny; iy++ {
-			// Get image data reader
-			stripReader, err := im.StripReader(iy)
-			if err != nil {
-				return fmt.Errorf("cannot get strip %d: %v", iy, err)
-			}
+	// decode reads the j-th segment intersecting with rect, and copies the visible part to buffer.
+	decode := func(j int, segmentBuf []byte) error {
+		ix := ix0 + j%nx
+		iy := iy0 + j/nx
+		n, err := im.readSegment(l, iy*l.nx+ix, segmentBuf, opts)
+		if err != nil {
+			return err
+		}
+		l.copySegment(buffer, rect, segmentBuf[:n], l.segmentBounds(ix, iy), im.Header.ByteOrder)
+		return nil
+	}
 
-			// Last strip may not be a full strip, and is not padded
-			if iy == ny-1 {
-				numRows = height - iy*int(rowsPerStrip)
+	workers := opts.workers()
+	if workers > numSegments {
+		workers = numSegments
+	}
+	if workers == 1 {
+		segmentBuf := make([]byte, l.segmentBytes())
+		for j := 0; j < numSegments; j++ {
+			if err := decode(j, segmentBuf); err != nil {
+				return err
 			}
+		}
+		return nil
+	}
 
-			// Process rows in the strip
-			for iRow := 0; iRow < numRows; iRow++ {
-				_, err := io.ReadFull(stripReader, rowBuf)
-				if err != nil {
-					return fmt.Errorf("cannot read strip %d row %d: %v", iy, iRow, err)
-				}
-
-				if buf, ok := buffer.([]uint8); ok {
-					copy(buf[destOffset:destOffset+samplesPerRow], rowBuf)
-					destOffset += samplesPerRow
-				}
-				if buf, ok := buffer.([]uint16); ok {
-					// Interpret data as uint16
-					var rowBuf16 []uint16
-					if im.Header.ByteOrder == binary.LittleEndian {
-						rowBuf16 = (*(*[1 << 48]uint16)(unsafe.Pointer(&rowBuf[0])))[:samplesPerRow]
-						copy(buf[destOffset:destOffset+samplesPerRow], rowBuf16)
-						destOffset += samplesPerRow
-					} else {
-						srcOffset := 0
-						for i := samplesPerRow; i > 0; i-- {
-							buf[destOffset] = ((uint16)(rowBuf[srcOffset]) << 8) | (uint16)(rowBuf[srcOffset+1])
-							srcOffset += 2
-							destOffset++
-						}
-					}
-
+	// Segments are dispatched in order, and dispatching stops after a failure,
+	// so that all segments before the failed one are decoded.
+	errs := make([]error, numSegments)
+	var failed int32
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			segmentBuf := make([]byte, l.segmentBytes())
+			for j := range jobs {
+				errs[j] = decode(j, segmentBuf)
+				if errs[j] != nil {
+					atomic.StoreInt32(&failed, 1)
 				}
 			}
+		}()
+	}
+	for j := 0; j < numSegments && atomic.LoadInt32(&failed) == 0; j++ {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
 
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		return nil
+// readSegment reads decompressed data of a strip or tile to buf, and returns the number of bytes read.
+//
+// The last strip is not padded, so it can be shorter than a full strip.
+// Sparse segments are filled with the fill value of opts.
+func (im *Image) readSegment(l *segmentLayout, index int, buf []byte, opts *DecodeOptions) (int, error) {
+	n := l.segmentBytes()
+	if !l.tiled {
+		rows := l.segmentBounds(0, index).Dy()
+		n = rows * l.segmentWidth * l.samplesPerPixel * l.bytesPerSample
 	}
 
-	//
-	// Tile mode
-	//
-	numTiles := im.NumTiles()
-	if numTiles > 0 {
-		// Find expected number of tiles
-		tileHeight, ok := im.Tag[TagTileLength].Uint()
-		if !ok {
-			return FormatError("invalid TagTileLength")
+	if l.isSparse(index) {
+		fill, err := im.fillSample(l, opts)
+		if err != nil {
+			return 0, fmt.Errorf("cannot fill %s %d: %v", l.segmentName(), index, err)
 		}
-		ny := (int)(math.Ceil((float64)(height) / (float64)(tileHeight)))
-		tileWidth, ok := im.Tag[TagTileWidth].Uint()
-		if !ok {
-			return FormatError("invalid TagTileWidth")
+		for i := 0; i < n; i += len(fill) {
+			copy(buf[i:], fill)
 		}
-		nx := (int)(math.Ceil((float64)(width) / (float64)(tileWidth)))
+		return n, nil
+	}
 
-		if nx*ny != numTiles {
-			return FormatError(fmt.Sprintf("expecting %d tiles, found %d tiles", nx*ny, numTiles))
-		}
+	r, err := im.decodeRawSegment(l.rawSegmentReader(im.rd, index))
+	if err != nil {
+		return 0, fmt.Errorf("cannot get %s %d: %v", l.segmentName(), index, err)
+	}
+	defer r.Close()
 
-		// Process tiles
-		samplesPerTile := int(tileWidth*tileHeight) * samplePerPixel
-		tileBuf := make([]byte, samplesPerTile*bitDepth/8)
-		for iy := 0; iy < ny; iy++ {
-			for ix := 0; ix < nx; ix++ {
-				// Read all pixels of the tile
-				tileReader, err := im.TileReader(iy*nx + ix)
-				if err != nil {
-					return fmt.Errorf("cannot get tile %d: %v", iy*nx+ix, err)
-				}
-				_, err = io.ReadFull(tileReader, tileBuf)
-				if err != nil {
-					return fmt.Errorf("cannot read tile %d: %v", iy*nx+ix, err)
-				}
+	_, err = io.ReadFull(r, buf[:n])
+	if err != nil {
+		return 0, fmt.Errorf("cannot read %s %d: %v", l.segmentName(), index, err)
+	}
+	l.undoPredictor(buf[:n], im.Header.ByteOrder)
+	return n, nil
+}
+
+// DecodeTile decodes the tile at column ix and row iy, and copy data to buffer.
+//
+// It returns the part of the image covered by the tile. The padding of tiles on the right and bottom edges
+// of the image is removed, so that buffer holds rect.Dx()*rect.Dy()*SamplesPerPixel() samples in row-major order.
+// A buffer for a full tile can be reused for all tiles. The buffer is of the same type as required by DecodeImage.
+// A sparse tile is filled with the default fill value of DecodeOptions.
+func (im *Image) DecodeTile(ix, iy int, buffer interface{}) (rect image.Rectangle, err error) {
+	return im.DecodeTileWithOptions(ix, iy, buffer, nil)
+}
 
-				// Set up tile coordinate and bounds
-				tileX0 := ix * int(tileWidth)
-				tileY0 := iy * int(tileHeight)
-				destOffset := (tileY0*width + tileX0) * samplePerPixel
-				destStride := width * samplePerPixel
+// DecodeTileWithOptions is like DecodeTile, with options such as the fill value of sparse tiles.
+// Workers is ignored, since a tile is decoded by the calling goroutine.
+func (im *Image) DecodeTileWithOptions(ix, iy int, buffer interface{}, opts *DecodeOptions) (rect image.Rectangle, err error) {
+	l, err := im.decodeLayout()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	if !l.tiled {
+		return image.Rectangle{}, fmt.Errorf("image is not organized in tiles")
+	}
+	if ix < 0 || ix >= l.nx || iy < 0 || iy >= l.ny {
+		return image.Rectangle{}, fmt.Errorf("tile (%d, %d) out of range", ix, iy)
+	}
+	return im.decodeSegment(l, ix, iy, buffer, opts)
+}
 
-				tileDX := int(tileWidth) * samplePerPixel
-				tileDY := int(tileHeight)
-				if ix == nx-1 {
-					tileDX = (width - ix*int(tileWidth)) * samplePerPixel
-				}
-				if iy == ny-1 {
-					tileDY = height - iy*int(tileHeight)
-				}
-				srcOffset := 0
-				srcStride := int(tileWidth) * samplePerPixel
-
-				// Copy tile data line-by-line
-				for iRow := 0; iRow < tileDY; iRow++ {
-					if buf, ok := buffer.([]uint8); ok {
-						copy(buf[destOffset:destOffset+tileDX], tileBuf[srcOffset:srcOffset+tileDX])
-						destOffset += destStride
-						srcOffset += srcStride
-					}
-					if buf, ok := buffer.([]uint16); ok {
-						// Interpret data as uint16
-						var tileBuf16 []uint16
-						if im.Header.ByteOrder == binary.LittleEndian {
-							tileBuf16 = (*(*[1 << 48]uint16)(unsafe.Pointer(&tileBuf[2*srcOffset])))[:tileDX]
-							copy(buf[destOffset:destOffset+tileDX], tileBuf16[:tileDX])
-							destOffset += destStride
-							srcOffset += srcStride
-						} else {
-							for i := 0; i < tileDX; i++ {
-								buf[destOffset+i] = ((uint16)(tileBuf[2*(srcOffset+i)]) << 8) | (uint16)(tileBuf[2*(srcOffset+i)+1])
-							}
-							destOffset += destStride
-							srcOffset += srcStride
-						}
-					}
-				}
-			}
-		}
-		return nil
+// DecodeStrip decodes the strip at given index, and copy data to buffer.
+//
+// It returns the part of the image covered by the strip. The last strip may be shorter than others,
+// and the buffer holds rect.Dx()*rect.Dy()*SamplesPerPixel() samples in row-major order.
+// A buffer for a full strip can be reused for all strips. The buffer is of the same type as required by DecodeImage.
+// A sparse strip is filled with the default fill value of DecodeOptions.
+func (im *Image) DecodeStrip(index int, buffer interface{}) (rect image.Rectangle, err error) {
+	return im.DecodeStripWithOptions(index, buffer, nil)
+}
+
+// DecodeStripWithOptions is like DecodeStrip, with options such as the fill value of sparse strips.
+// Workers is ignored, since a strip is decoded by the calling goroutine.
+func (im *Image) DecodeStripWithOptions(index int, buffer interface{}, opts *DecodeOptions) (rect image.Rectangle, err error) {
+	l, err := im.decodeLayout()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	if l.tiled {
+		return image.Rectangle{}, fmt.Errorf("image is not organized in strips")
 	}
+	if index < 0 || index >= l.ny {
+		return image.Rectangle{}, fmt.Errorf("strip %d out of range", index)
+	}
+	return im.decodeSegment(l, 0, index, buffer, opts)
+}
 
-	return fmt.Errorf("no strip or tile found")
+func (im *Image) decodeSegment(l *segmentLayout, ix, iy int, buffer interface{}, opts *DecodeOptions) (rect image.Rectangle, err error) {
+	rect = l.segmentBounds(ix, iy)
+	buffer, err = l.sliceBuffer(buffer, rect.Dx()*rect.Dy())
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	segmentBuf := make([]byte, l.segmentBytes())
+	n, err := im.readSegment(l, iy*l.nx+ix, segmentBuf, opts)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	l.copySegment(buffer, rect, segmentBuf[:n], rect, im.Header.ByteOrder)
+	return rect, nil
 }
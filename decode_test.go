@@ -3,9 +3,11 @@ package tiff_test
 import (
 	"bytes"
 	"encoding/binary"
+	"image"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	tiff "github.com/Andeling/tiff"
@@ -404,6 +406,52 @@ func BenchmarkDecode_RGB8_LZW(b *testing.B) {
 	}
 }
 
+func TestDecode_DefaultRowsPerStrip(t *testing.T) {
+	f, err := ioutil.TempFile("", "tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// Without RowsPerStrip, the encoder writes the whole image as one strip
+	data := []uint8{1, 2, 3, 4, 5, 6}
+	enc := tiff.NewEncoder(f)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 3)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	if err := im.EncodeImage(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := tiff.NewDecoder(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := d.Iter()
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	im = it.Image()
+	if im.Tag[tiff.TagRowsPerStrip] != nil {
+		t.Fatalf("RowsPerStrip written")
+	}
+	got := make([]uint8, len(data))
+	if err := im.DecodeImage(got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Errorf("decoded %v, want %v", got, data)
+	}
+}
+
 func TestDecode_RGB16(t *testing.T) {
 	f_groundtruth, err := os.Open("../tiff_testdata/rgb16_le.bin")
 	if err != nil {
@@ -518,3 +566,237 @@ func TestDecode_RGB8(t *testing.T) {
 		}()
 	}
 }
+
+// encodeTestImage encodes a 16-bit RGB image with pixel values derived from their coordinates.
+func encodeTestImage(t *testing.T, width, height int, setup func(im *tiff.Image)) ([]byte, []uint16) {
+	data := make([]uint16, width*height*3)
+	for i := range data {
+		data[i] = uint16(i)
+	}
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(width, height)
+	im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{16, 16, 16})
+	if setup != nil {
+		setup(im)
+	}
+	if err := im.EncodeImage(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return w.buf, data
+}
+
+func TestDecodeRegion(t *testing.T) {
+	const width, height = 37, 29
+	file, data := encodeTestImage(t, width, height, func(im *tiff.Image) {
+		im.SetRowsPerStrip(4)
+		im.SetCompression(tiff.CompressionDeflate)
+	})
+	im := decodeFirst(t, file)
+
+	for _, rect := range []image.Rectangle{
+		image.Rect(0, 0, width, height),
+		image.Rect(3, 5, 20, 6),
+		image.Rect(10, 7, 37, 29),
+		image.Rect(36, 28, 37, 29),
+	} {
+		buf := make([]uint16, rect.Dx()*rect.Dy()*3)
+		if err := im.DecodeRegion(rect, buf); err != nil {
+			t.Fatalf("%v: %v", rect, err)
+		}
+		i := 0
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				for c := 0; c < 3; c++ {
+					if want := data[(y*width+x)*3+c]; buf[i] != want {
+						t.Fatalf("%v: sample %d at (%d, %d) is %d, want %d", rect, c, x, y, buf[i], want)
+					}
+					i++
+				}
+			}
+		}
+	}
+
+	if err := im.DecodeRegion(image.Rect(30, 20, 40, 30), make([]uint16, 300)); err == nil {
+		t.Error("expecting error for region out of bounds")
+	}
+}
+
+func TestDecodeStrip(t *testing.T) {
+	const width, height = 37, 29
+	file, data := encodeTestImage(t, width, height, func(im *tiff.Image) {
+		im.SetRowsPerStrip(8)
+	})
+	im := decodeFirst(t, file)
+
+	buf := make([]uint16, width*im.RowsPerStrip()*3)
+	for i := 0; i < im.NumStrips(); i++ {
+		rect, err := im.DecodeStrip(i, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := image.Rect(0, 8*i, width, 8*i+8).Intersect(image.Rect(0, 0, width, height)); rect != want {
+			t.Fatalf("strip %d covers %v, want %v", i, rect, want)
+		}
+		offset := rect.Min.Y * width * 3
+		n := rect.Dx() * rect.Dy() * 3
+		if !reflect.DeepEqual(buf[:n], data[offset:offset+n]) {
+			t.Fatalf("strip %d: data inconsistent with source", i)
+		}
+	}
+	if _, err := im.DecodeTile(0, 0, buf); err == nil {
+		t.Error("expecting error for DecodeTile on a stripped image")
+	}
+}
+
+func TestDecode_Predictor(t *testing.T) {
+	const width, height = 7, 5
+	data := make([]uint8, width*height*3)
+	for i := range data {
+		data[i] = uint8(i * i)
+	}
+	// Apply horizontal differencing before encoding
+	diff := make([]uint8, len(data))
+	for y := 0; y < height; y++ {
+		for i := 0; i < width*3; i++ {
+			diff[y*width*3+i] = data[y*width*3+i]
+			if i >= 3 {
+				diff[y*width*3+i] -= data[y*width*3+i-3]
+			}
+		}
+	}
+
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(width, height)
+	im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+	im.SetRowsPerStrip(2)
+	im.SetTag(tiff.TagPredictor, tiff.TagTypeShort, uint16(tiff.PredictorHorizontal))
+	if err := im.EncodeImage(diff); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]uint8, len(data))
+	if err := decodeFirst(t, w.buf).DecodeImage(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(buf, data) {
+		t.Errorf("decoded %v, want %v", buf, data)
+	}
+}
+
+func TestDecode_Workers(t *testing.T) {
+	const width, height = 37, 29
+	file, data := encodeTestImage(t, width, height, func(im *tiff.Image) {
+		im.SetRowsPerStrip(3)
+		im.SetCompression(tiff.CompressionDeflate)
+	})
+
+	buf := make([]uint16, len(data))
+	opts := &tiff.DecodeOptions{Workers: 4}
+	if err := decodeFirst(t, file).DecodeImageWithOptions(buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(buf, data) {
+		t.Fatal("data inconsistent with source")
+	}
+
+	// Corrupt strips 3 and 7, the error should always be reported for strip 3.
+	offsets, _ := decodeFirst(t, file).Tag[tiff.TagStripOffsets].UintSlice()
+	for _, i := range []int{7, 3} {
+		copy(file[offsets[i]:], "corrupted")
+	}
+	for i := 0; i < 10; i++ {
+		err := decodeFirst(t, file).DecodeImageWithOptions(buf, opts)
+		if err == nil || !strings.Contains(err.Error(), "strip 3:") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestDecode_SparseTiles(t *testing.T) {
+	const width, height = 40, 20
+	// Only tile (1, 0) is written, tiles (0, 0), (0, 1) and (1, 1) are sparse
+	encode := func(noData string) []byte {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{16})
+		im.SetTileWidthHeight(32, 16)
+		if noData != "" {
+			im.SetTag(tiff.TagGDALNoData, tiff.TagTypeASCII, noData)
+		}
+		tw, err := im.NewTileWriter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tile := make([]uint16, 8*16)
+		for i := range tile {
+			tile[i] = 1000
+		}
+		if err := tw.WriteTile(1, 0, tile); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return w.buf
+	}
+
+	fillValue := 42.0
+	tests := []struct {
+		noData string
+		opts   *tiff.DecodeOptions
+		want   uint16
+	}{
+		{"", nil, 0},
+		{"255", nil, 255},
+		{"255", &tiff.DecodeOptions{FillValue: &fillValue}, 42},
+		{"", &tiff.DecodeOptions{FillValue: &fillValue, Workers: 2}, 42},
+	}
+	for _, test := range tests {
+		im := decodeFirst(t, encode(test.noData))
+		if got := im.SparseSegments(); !reflect.DeepEqual(got, []int{0, 2, 3}) {
+			t.Fatalf("SparseSegments() = %v, want [0 2 3]", got)
+		}
+		buf := make([]uint16, width*height)
+		if err := im.DecodeImageWithOptions(buf, test.opts); err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				want := test.want
+				if x >= 32 && y < 16 {
+					want = 1000
+				}
+				if buf[y*width+x] != want {
+					t.Fatalf("GDALNoData %q: pixel (%d, %d) = %d, want %d", test.noData, x, y, buf[y*width+x], want)
+				}
+			}
+		}
+
+		// The same fill value for a single tile
+		tile := make([]uint16, 32*16)
+		rect, err := im.DecodeTileWithOptions(1, 1, tile, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := rect.Dx() * rect.Dy(); n != 8*4 || tile[0] != test.want || tile[n-1] != test.want {
+			t.Errorf("GDALNoData %q: tile (1, 1) of %v starts with %d", test.noData, rect, tile[0])
+		}
+	}
+
+	// Values that cannot be represented in 16-bit unsigned samples
+	for _, noData := range []string{"-9999", "nan", "65536", "1.5"} {
+		buf := make([]uint16, width*height)
+		if err := decodeFirst(t, encode(noData)).DecodeImage(buf); err == nil {
+			t.Errorf("GDALNoData %q: no error", noData)
+		}
+	}
+}
@@ -1,20 +1,33 @@
 package tiff
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
-	"unsafe"
+	"sync"
 
 	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
 )
 
 type Encoder struct {
 	Header *Header
 
-	w      io.WriteSeeker
-	offset int64 // Current offset
+	w       io.WriteSeeker
+	wa      io.WriterAt   // Writer of NewEncoderAt, or nil
+	stream  *streamWriter // Buffer of w for NewStreamEncoder, or nil
+	mu      sync.Mutex    // Protects offset and writes to w
+	offset  int64         // Current offset
+	last    *Image        // Last encoded image in the chain of IFDs
+	images  []*Image      // Images whose IFDs are written by Close, with NewEncoderAt or LayoutCOG
+	layout  Layout        // Arrangement of IFDs and image data
+	workers int           // Number of segments compressed concurrently
+
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdErr     error
 }
 
 func NewEncoder(w io.WriteSeeker) *Encoder {
@@ -23,19 +36,91 @@ func NewEncoder(w io.WriteSeeker) *Encoder {
 			ByteOrder: binary.LittleEndian,
 			Version:   VersionClassicTIFF,
 		},
-		w: w,
+		w:       w,
+		workers: 1,
 	}
 }
 
+// DefaultStreamBufferSize is the default limit of data kept in memory by an Encoder of NewStreamEncoder.
+const DefaultStreamBufferSize = 256 << 20
+
+// NewStreamEncoder returns an Encoder writing to w without seeking, such as a pipe or an HTTP response.
+//
+// The IFD of each image is written after its image data. Uncompressed image data have a known size,
+// so the offset of the IFD is known before them, and they are written to w as they are encoded.
+// Compressed image data are kept in memory until the image is complete, because the header or the previous IFD,
+// which are written before them, point to the IFD following them. Sub-images are kept in memory with the IFD
+// of their parent until the next image. Encoding fails when an image needs more than DefaultStreamBufferSize bytes
+// of memory, which is changed by SetStreamBufferSize. Close must be called to write the IFD of the last image.
+func NewStreamEncoder(w io.Writer) *Encoder {
+	stream := &streamWriter{w: w, limit: DefaultStreamBufferSize}
+	enc := NewEncoder(stream)
+	enc.stream = stream
+	return enc
+}
+
+// NewEncoderAt returns an Encoder writing to w at increasing offsets, without reading back or overwriting
+// image data.
+//
+// Image data of all images are written first, and all IFDs are written after them by Close, which also
+// writes the file header. Writes of image data can run concurrently, such as from multiple TileWriters.
+func NewEncoderAt(w io.WriterAt) *Encoder {
+	enc := NewEncoder(nil)
+	enc.wa = w
+	return enc
+}
+
 func (enc *Encoder) SetByteOrder(byteOrder binary.ByteOrder) {
 	enc.Header.ByteOrder = byteOrder
 }
 
+// SetVersion sets the TIFF version of the file. It must be called before encoding the first image.
+//
+// With VersionAuto, the file is written as BigTIFF if the uncompressed image data of the first image
+// and its overviews are larger than 4 GiB, otherwise as ClassicTIFF. Only the first image is considered, because the header
+// is written before the following images are known. If later images make the file larger than 4 GiB,
+// encoding them fails with an error, and VersionBigTIFF must be set instead.
 func (enc *Encoder) SetVersion(version Version) {
 	enc.Header.Version = version
 }
 
+// SetStreamBufferSize sets the maximum number of bytes kept in memory by an Encoder of NewStreamEncoder,
+// which holds the last IFD, and the compressed image data of one image. It has no effect on other Encoders.
+func (enc *Encoder) SetStreamBufferSize(size int64) {
+	if enc.stream != nil {
+		enc.stream.limit = size
+	}
+}
+
+// SetWorkers sets the number of strips or tiles compressed concurrently.
+//
+// Compressed segments are still written to the file in order. The default is 1,
+// which compresses segments sequentially in the calling goroutine.
+func (enc *Encoder) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	enc.workers = workers
+}
+
 func (enc *Encoder) Close() error {
+	if enc.layout == LayoutCOG {
+		if err := enc.writeCOG(); err != nil {
+			return err
+		}
+	} else if enc.wa != nil {
+		if err := enc.writeIFDs(); err != nil {
+			return err
+		}
+	}
+	if enc.stream != nil {
+		if err := enc.stream.commit(enc.offset); err != nil {
+			return err
+		}
+	}
+	if enc.zstdEncoder != nil {
+		return enc.zstdEncoder.Close()
+	}
 	return nil
 }
 
@@ -46,46 +131,590 @@ func (enc *Encoder) NewImage() *Image {
 	}
 }
 
+// write writes p at the current offset, and returns the offset where p is written.
+// It is safe for concurrent use.
+//
+// In ClassicTIFF, it fails before writing p if the offset or the size of p cannot be stored in 32 bits.
+func (enc *Encoder) write(p []byte) (offset int64, err error) {
+	enc.mu.Lock()
+	offset = enc.offset
+	if enc.Header.Version == VersionClassicTIFF && (offset > math.MaxUint32 || int64(len(p)) > math.MaxUint32) {
+		enc.mu.Unlock()
+		return 0, errClassicTIFFOverflow(offset)
+	}
+	if enc.wa != nil {
+		// Reserve the range of p, and write it concurrently with other writes
+		enc.offset += int64(len(p))
+		enc.mu.Unlock()
+		_, err = enc.wa.WriteAt(p, offset)
+		if err != nil {
+			return 0, err
+		}
+		return offset, nil
+	}
+	defer enc.mu.Unlock()
+	_, err = enc.w.Write(p)
+	if err != nil {
+		return 0, err
+	}
+	enc.offset += int64(len(p))
+	return offset, nil
+}
+
+// writeAt overwrites data at offset, and restores the current offset.
+func (enc *Encoder) writeAt(p []byte, offset int64) error {
+	if enc.wa != nil {
+		_, err := enc.wa.WriteAt(p, offset)
+		return err
+	}
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+	_, err := enc.w.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(p)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Seek(enc.offset, io.SeekStart)
+	return err
+}
+
+// deferIFDs reports whether IFDs are written by Close, rather than before image data.
+func (enc *Encoder) deferIFDs() bool {
+	return enc.wa != nil || enc.layout == LayoutCOG
+}
+
+// writeIFDs writes the IFDs of all images of NewEncoderAt after image data, and the header pointing to them.
+func (enc *Encoder) writeIFDs() error {
+	if len(enc.images) == 0 {
+		return nil
+	}
+	// IFDs in file order, with IFDs of sub-images after the IFD of their parent
+	var ifds []*Image
+	for _, im := range enc.images {
+		ifds = append(ifds, im)
+		for _, sub := range im.SubImage {
+			if sub.NumStrips() > 0 || sub.NumTiles() > 0 {
+				ifds = append(ifds, sub)
+			}
+		}
+	}
+
+	// Find offsets of IFDs, whose sizes do not depend on the offsets they contain
+	start := wordAlign(enc.offset)
+	offset := start
+	for _, im := range ifds {
+		ifd, err := im.EncodeTags(offset)
+		if err != nil {
+			return err
+		}
+		offset = wordAlign(offset + int64(len(ifd)))
+	}
+	for i, im := range enc.images {
+		if i < len(enc.images)-1 {
+			im.OffsetNext = enc.images[i+1].Offset
+		}
+		if len(im.SubImage) > 0 {
+			if err := im.setSubIFDs(im.subIFDOffsets()); err != nil {
+				return err
+			}
+		}
+	}
+
+	var buf []byte
+	for _, im := range ifds {
+		ifd, err := im.EncodeTags(im.Offset)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, ifd...)
+		buf = append(buf, make([]byte, wordAlign(int64(len(buf)))-int64(len(buf)))...)
+	}
+	_, err := enc.wa.WriteAt(buf, start)
+	if err != nil {
+		return err
+	}
+	enc.offset = start + int64(len(buf))
+	enc.images = nil
+
+	enc.Header.OffsetFirstIFD = start
+	return enc.writeAt(enc.Header.encodeBytes(), 0)
+}
+
+// streamWriter adapts an io.Writer to io.WriteSeeker for NewStreamEncoder.
+//
+// Data after the committed offset are kept in memory, where they can still be overwritten,
+// until they are committed and written to w.
+type streamWriter struct {
+	w         io.Writer
+	committed int64  // Offset of buf
+	buf       []byte // Data not yet committed
+	offset    int64  // Current offset
+	limit     int64  // Maximum size of buf
+	direct    bool   // Data are written to w without being kept in memory, while buf is empty
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.direct {
+		n, err := s.w.Write(p)
+		s.offset += int64(n)
+		s.committed = s.offset
+		return n, err
+	}
+	start := s.offset - s.committed
+	if start+int64(len(p)) > s.limit {
+		return 0, fmt.Errorf("image of a stream needs more than %d bytes of memory: increase it with SetStreamBufferSize", s.limit)
+	}
+	if start == int64(len(s.buf)) {
+		s.buf = append(s.buf, p...)
+	} else {
+		// Overwrite, possibly beyond the end of buf
+		n := copy(s.buf[start:], p)
+		s.buf = append(s.buf, p[n:]...)
+	}
+	s.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (s *streamWriter) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, fmt.Errorf("unsupported whence %d", whence)
+	}
+	if offset < s.committed || offset > s.committed+int64(len(s.buf)) {
+		return 0, fmt.Errorf("cannot seek to offset %d of a stream", offset)
+	}
+	s.offset = offset
+	return offset, nil
+}
+
+// commit writes data before offset to w.
+func (s *streamWriter) commit(offset int64) error {
+	n := offset - s.committed
+	if n <= 0 {
+		return nil
+	}
+	_, err := s.w.Write(s.buf[:n])
+	if err != nil {
+		return err
+	}
+	// Copy the remaining data to release the memory of committed data
+	s.buf = append([]byte(nil), s.buf[n:]...)
+	s.committed = offset
+	return nil
+}
+
+// errClassicTIFFOverflow reports an offset which cannot be stored in the 32-bit offsets of ClassicTIFF.
+func errClassicTIFFOverflow(offset int64) error {
+	return fmt.Errorf("offset %d overflows ClassicTIFF, which is limited to 4 GiB: use VersionBigTIFF", offset)
+}
+
+// resolveVersion chooses between ClassicTIFF and BigTIFF with VersionAuto, before the header is written.
+func (enc *Encoder) resolveVersion(im *Image, l *segmentLayout) {
+	if enc.Header.Version != VersionAuto {
+		return
+	}
+	// Uncompressed image data of the image and its overviews, and 1 MiB reserved for IFDs
+	size := l.dataSize(l.width, l.height)
+	if im.overviews != nil {
+		width, height := l.width, l.height
+		for level := 0; level < im.overviews.levels(l); level++ {
+			width, height = (width+1)/2, (height+1)/2
+			size += l.dataSize(width, height)
+		}
+	}
+	if size+1<<20 > math.MaxUint32 {
+		enc.Header.Version = VersionBigTIFF
+	} else {
+		enc.Header.Version = VersionClassicTIFF
+	}
+}
+
+// dataSize returns the size of the uncompressed data of an image of given size, stored in segments like l.
+// Tiles at the right and bottom edges are padded to the full tile size.
+func (l *segmentLayout) dataSize(width, height int) int64 {
+	if l.tiled {
+		width = (width + l.segmentWidth - 1) / l.segmentWidth * l.segmentWidth
+		height = (height + l.segmentHeight - 1) / l.segmentHeight * l.segmentHeight
+	}
+	return int64(width) * int64(height) * int64(l.samplesPerPixel) * int64(l.bytesPerSample)
+}
+
+// offsetTagType returns the type of tags holding offsets, which is Long in ClassicTIFF and Long8 in BigTIFF.
+func (enc *Encoder) offsetTagType() TagType {
+	if enc.Header.Version == VersionBigTIFF {
+		return TagTypeLong8
+	}
+	return TagTypeLong
+}
+
+// linkIFD points the offset to next IFD of the last encoded image to the IFD of im.
+func (enc *Encoder) linkIFD(im *Image) error {
+	last := enc.last
+	enc.last = im
+	if last == nil {
+		if enc.stream == nil {
+			return nil
+		}
+		// The IFD of the first image of a stream follows its image data
+		if enc.Header.Version == VersionClassicTIFF && im.Offset > math.MaxUint32 {
+			return errClassicTIFFOverflow(im.Offset)
+		}
+		enc.Header.OffsetFirstIFD = im.Offset
+		return enc.writeAt(enc.Header.encodeBytes(), 0)
+	}
+	last.OffsetNext = im.Offset
+
+	var buf []byte
+	var offset int64
+	if enc.Header.Version == VersionClassicTIFF {
+		if last.OffsetNext > math.MaxUint32 {
+			return errClassicTIFFOverflow(last.OffsetNext)
+		}
+		buf = make([]byte, 4)
+		enc.Header.ByteOrder.PutUint32(buf, uint32(last.OffsetNext))
+		offset = last.Offset + 2 + 12*int64(len(last.Tag))
+	} else {
+		buf = make([]byte, 8)
+		enc.Header.ByteOrder.PutUint64(buf, uint64(last.OffsetNext))
+		offset = last.Offset + 8 + 20*int64(len(last.Tag))
+	}
+	return enc.writeAt(buf, offset)
+}
+
 func (im *Image) EncodeImage(buffer interface{}) error {
+	l, err := im.newSegmentLayout()
+	if err != nil {
+		return err
+	}
+	if err := l.checkBuffer(buffer, l.width*l.height); err != nil {
+		return err
+	}
+
+	// Overviews written as SubIFDs need to be known before writing the IFD
+	firstOverview := len(im.SubImage)
+	if im.overviews != nil && im.overviews.SubIFDs {
+		for level := 0; level < im.overviews.levels(l); level++ {
+			im.AddSubImage()
+		}
+	}
+
+	err = im.beginEncode(l)
+	if err != nil {
+		return err
+	}
+
+	//
+	// Write strips or tiles
+	//
+	numSegments := l.nx * l.ny
+	offsets := make([]uint64, numSegments)
+	byteCounts := make([]uint64, numSegments)
+	err = im.writeSegments(l, buffer, 0, func(index int, offset int64, byteCount int) {
+		offsets[index] = uint64(offset)
+		byteCounts[index] = uint64(byteCount)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = im.finishEncode(l, offsets, byteCounts)
+	if err != nil || im.overviews == nil {
+		return err
+	}
+	return im.encodeOverviews(l, buffer, firstOverview)
+}
+
+// segmentTagIDs returns the tags for offsets and byte counts of segments.
+func (l *segmentLayout) segmentTagIDs() (offsetTagID TagID, byteCountTagID TagID) {
+	if l.tiled {
+		return TagTileOffsets, TagTileByteCounts
+	}
+	return TagStripOffsets, TagStripByteCounts
+}
+
+// beginEncode writes the file header if needed and the IFD of the image,
+// with placeholders for the offsets and byte counts of segments.
+func (im *Image) beginEncode(l *segmentLayout) error {
+	// Compression
+	if im.Tag[TagCompression] == nil {
+		im.SetTag(TagCompression, TagTypeShort, CompressionNone)
+	}
+
+	//
+	// Write Header
+	//
+	im.enc.mu.Lock()
+	first := im.enc.offset == 0 && len(im.enc.images) == 0
+	im.enc.mu.Unlock()
+	if first {
+		im.enc.resolveVersion(im, l)
+		if im.Header.Version == VersionClassicTIFF {
+			im.Header.OffsetFirstIFD = 8
+		} else {
+			im.Header.OffsetFirstIFD = 16
+		}
+
+		// With LayoutCOG, the header is written by Close
+		if im.enc.layout != LayoutCOG {
+			_, err := im.enc.write(im.Header.encodeBytes())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if im.enc.layout == LayoutCOG && !l.tiled {
+		return fmt.Errorf("COG layout requires tiled images")
+	}
+	if im.parent != nil {
+		if im.enc.layout == LayoutCOG {
+			return fmt.Errorf("COG layout does not support SubIFDs")
+		}
+		if im.parent.Tag[TagSubIFDs] == nil {
+			return fmt.Errorf("sub-image is encoded before its parent image")
+		}
+	}
+
+	// Placeholders for offsets and byte counts of segments
+	numSegments := l.nx * l.ny
+	err := im.setSegmentTags(l, make([]uint64, numSegments), make([]uint64, numSegments))
+	if err != nil {
+		return err
+	}
+	if len(im.SubImage) > 0 {
+		err = im.setSubIFDs(make([]uint64, len(im.SubImage)))
+		if err != nil {
+			return err
+		}
+	}
+	if im.enc.layout == LayoutCOG {
+		im.segments = make([][]byte, numSegments)
+	}
+	if im.enc.deferIFDs() {
+		// The IFD is written by Close, with IFDs of sub-images after the IFD of their parent
+		if im.parent == nil {
+			im.enc.mu.Lock()
+			im.enc.images = append(im.enc.images, im)
+			im.enc.mu.Unlock()
+		}
+		return nil
+	}
+	if im.enc.stream != nil {
+		return im.beginStream(l)
+	}
+
+	//
+	// Write IFD tags
+	//
+	buf, err := im.EncodeTags(im.enc.offset)
+	if err != nil {
+		return err
+	}
+	_, err = im.enc.write(buf)
+	if err != nil {
+		return err
+	}
+	if im.parent != nil {
+		// Sub-images are not in the chain of IFDs, and linked to their parent by finishEncode
+		return nil
+	}
+	return im.enc.linkIFD(im)
+}
+
+// beginStream prepares an image of NewStreamEncoder, whose IFD is written after its image data by finishStream.
+//
+// The IFD of an uncompressed image in the chain of IFDs follows image data of a known size. The header
+// or the previous IFD are linked to it and written, and the image data are then written directly to the stream.
+func (im *Image) beginStream(l *segmentLayout) error {
+	enc := im.enc
+	if im.parent != nil || im.Compression() != CompressionNone {
+		return nil
+	}
+	im.Offset = wordAlign(enc.offset + l.dataSize(l.width, l.height))
+	if err := enc.linkIFD(im); err != nil {
+		return err
+	}
+	if err := enc.stream.commit(enc.offset); err != nil {
+		return err
+	}
+	enc.stream.direct = true
+	return nil
+}
+
+// finishStream writes the IFD of an image of NewStreamEncoder after its image data.
+//
+// The IFD is kept in memory until the next image is linked to it, or the Encoder is closed.
+func (im *Image) finishStream() error {
+	enc := im.enc
+	if enc.stream.direct {
+		enc.stream.direct = false
+	} else {
+		im.Offset = wordAlign(enc.offset)
+		if im.parent == nil {
+			if err := enc.linkIFD(im); err != nil {
+				return err
+			}
+			// Everything before the image data of im is complete
+			if err := enc.stream.commit(enc.offset); err != nil {
+				return err
+			}
+		}
+	}
+	// Tiles not written by a TileWriter leave a gap before the IFD
+	if err := enc.writePadding(im.Offset); err != nil {
+		return err
+	}
+	buf, err := im.EncodeTags(im.Offset)
+	if err != nil {
+		return err
+	}
+	_, err = enc.write(buf)
+	if err != nil || im.parent == nil {
+		return err
+	}
+	return im.linkSubIFD()
+}
+
+// setSegmentTags sets the offsets and byte counts of segments, as Long in ClassicTIFF and Long8 in BigTIFF.
+func (im *Image) setSegmentTags(l *segmentLayout, offsets []uint64, byteCounts []uint64) error {
+	offsetTagID, byteCountTagID := l.segmentTagIDs()
+	tagType := im.enc.offsetTagType()
+	if tagType == TagTypeLong8 {
+		im.SetTag(offsetTagID, tagType, offsets)
+		im.SetTag(byteCountTagID, tagType, byteCounts)
+		return nil
+	}
+	offsets32 := make([]uint32, len(offsets))
+	byteCounts32 := make([]uint32, len(byteCounts))
+	for i := range offsets {
+		if offsets[i] > math.MaxUint32 {
+			return errClassicTIFFOverflow(int64(offsets[i]))
+		}
+		offsets32[i] = uint32(offsets[i])
+		byteCounts32[i] = uint32(byteCounts[i])
+	}
+	im.SetTag(offsetTagID, tagType, offsets32)
+	im.SetTag(byteCountTagID, tagType, byteCounts32)
+	return nil
+}
+
+// finishEncode rewrites the IFD of the image with the offsets and byte counts of segments.
+func (im *Image) finishEncode(l *segmentLayout, offsets []uint64, byteCounts []uint64) error {
+	err := im.setSegmentTags(l, offsets, byteCounts)
+	if err != nil || im.enc.deferIFDs() {
+		return err
+	}
+	if im.enc.stream != nil {
+		return im.finishStream()
+	}
+
+	// Current workaround is to overwrite all tags
+	buf, err := im.EncodeTags(im.Offset)
+	if err != nil {
+		return err
+	}
+	err = im.enc.writeAt(buf, im.Offset)
+	if err != nil || im.parent == nil {
+		return err
+	}
+	return im.linkSubIFD()
+}
+
+// AddSubImage adds a sub-image, which is written as a SubIFD of the image.
+//
+// Sub-images need to be added before encoding the image, and are encoded after it.
+func (im *Image) AddSubImage() *Image {
+	sub := im.enc.NewImage()
+	sub.parent = im
+	im.SubImage = append(im.SubImage, sub)
+	return sub
+}
+
+// setSubIFDs sets the offsets of sub-images, as IFD in ClassicTIFF and IFD8 in BigTIFF.
+func (im *Image) setSubIFDs(offsets []uint64) error {
+	if im.enc.offsetTagType() == TagTypeLong8 {
+		return im.SetTag(TagSubIFDs, TagTypeIFD8, offsets)
+	}
+	offsets32 := make([]uint32, len(offsets))
+	for i := range offsets {
+		if offsets[i] > math.MaxUint32 {
+			return errClassicTIFFOverflow(int64(offsets[i]))
+		}
+		offsets32[i] = uint32(offsets[i])
+	}
+	return im.SetTag(TagSubIFDs, TagTypeIFD, offsets32)
+}
+
+// subIFDOffsets returns the offsets of the IFDs of sub-images, or 0 for sub-images not encoded.
+func (im *Image) subIFDOffsets() []uint64 {
+	offsets := make([]uint64, len(im.SubImage))
+	for i, sub := range im.SubImage {
+		offsets[i] = uint64(sub.Offset)
+	}
+	return offsets
+}
+
+// linkSubIFD rewrites the IFD of the parent image with the offset to the IFD of im.
+func (im *Image) linkSubIFD() error {
+	parent := im.parent
+	err := parent.setSubIFDs(parent.subIFDOffsets())
+	if err != nil {
+		return err
+	}
+	buf, err := parent.EncodeTags(parent.Offset)
+	if err != nil {
+		return err
+	}
+	return im.enc.writeAt(buf, parent.Offset)
+}
+
+// newSegmentLayout validates the size and pixel format of an image to be encoded,
+// and finds how the image data are divided into strips or tiles.
+func (im *Image) newSegmentLayout() (*segmentLayout, error) {
 	//
 	// Find width and height
 	//
 	width, height := im.WidthHeight()
 	if width == 0 {
-		return fmt.Errorf("invalid image width")
+		return nil, fmt.Errorf("invalid image width")
 	}
 	if height == 0 {
-		return fmt.Errorf("invalid image height")
+		return nil, fmt.Errorf("invalid image height")
+	}
+	l := &segmentLayout{
+		width:           width,
+		height:          height,
+		samplesPerPixel: im.SamplesPerPixel(),
+		predictor:       PredictorNone,
 	}
-	pixelCount := width * height
 
 	//
 	// Find bitDepth and samplePerPixel
 	//
-	samplePerPixel := im.SamplesPerPixel()
 	bitDepth := im.BitDepth()
 	switch bitDepth {
 	case 1:
-		return UnsupportedError("1-bit image is not yet supported")
-	case 8:
-		if buf, ok := buffer.([]uint8); ok {
-			if len(buf) != pixelCount*samplePerPixel {
-				return fmt.Errorf("wrong buffer size")
-			}
-		} else {
-			return fmt.Errorf("expecting []uint8")
-		}
-	case 16:
-		if buf, ok := buffer.([]uint16); ok {
-			if len(buf) != pixelCount*samplePerPixel {
-				return fmt.Errorf("wrong buffer size")
-			}
-		} else {
-			return fmt.Errorf("expecting []uint16")
-		}
+		return nil, UnsupportedError("1-bit image is not yet supported")
+	case 8, 16:
+		l.bytesPerSample = bitDepth / 8
 	default:
 		bitsPerSample := im.BitsPerSample()
-		return UnsupportedError(fmt.Sprintf("BitsPerSample of %v", bitsPerSample))
+		return nil, UnsupportedError(fmt.Sprintf("BitsPerSample of %v", bitsPerSample))
+	}
+
+	//
+	// ExtraSamples: one for each sample after the color components
+	//
+	extraSamples := len(im.ExtraSamples())
+	if n := colorSamples(im.Photometric()); n == 0 {
+		if extraSamples >= l.samplesPerPixel {
+			return nil, fmt.Errorf("too many ExtraSamples for %d samples per pixel", l.samplesPerPixel)
+		}
+	} else if l.samplesPerPixel < n {
+		return nil, fmt.Errorf("%d samples per pixel for %d color components", l.samplesPerPixel, n)
+	} else if extraSamples != l.samplesPerPixel-n {
+		return nil, fmt.Errorf("%d ExtraSamples for %d samples per pixel, want %d", extraSamples, l.samplesPerPixel, l.samplesPerPixel-n)
 	}
 
 	//
@@ -93,198 +722,247 @@ func (im *Image) EncodeImage(buffer interface{}) error {
 	//
 	planarConfig := im.PlanarConfig()
 	if planarConfig != PlanarConfigContig {
-		return UnsupportedError(fmt.Sprintf("PlanarConfiguration of %v", planarConfig))
-	}
-
-	// Compression
-	if im.Tag[TagCompression] == nil {
-		im.SetTag(TagCompression, TagTypeShort, CompressionNone)
+		return nil, UnsupportedError(fmt.Sprintf("PlanarConfiguration of %v", planarConfig))
 	}
 
 	//
 	// Strip mode or tile mode
 	//
-	numStrips := 0
-	numTiles := 0
-	nx := 0
-	ny := 0
-	var tileWidth, tileHeight, rowsPerStrip int
 	if im.Tag[TagTileWidth] != nil || im.Tag[TagTileLength] != nil {
 		//
 		// Tile mode
 		//
 		if im.Tag[TagTileWidth] != nil && im.Tag[TagTileLength] == nil {
-			return fmt.Errorf("missing TileLength tag")
+			return nil, fmt.Errorf("missing TileLength tag")
 		}
 		if im.Tag[TagTileWidth] == nil && im.Tag[TagTileLength] != nil {
-			return fmt.Errorf("missing TileWidth tag")
-		}
-		uintTileWidth, ok := im.Tag[TagTileWidth].Uint()
-		if !ok || uintTileWidth == 0 {
-			return fmt.Errorf("invalid TileWidth tag")
-		}
-		uintTileHeight, ok := im.Tag[TagTileLength].Uint()
-		if !ok || uintTileHeight == 0 {
-			return fmt.Errorf("invalid TileLength tag")
-		}
-		tileWidth = int(uintTileWidth)
-		tileHeight = int(uintTileHeight)
-		nx = (int)(math.Ceil((float64)(width) / (float64)(tileWidth)))
-		ny = (int)(math.Ceil((float64)(height) / (float64)(tileHeight)))
-		numTiles = nx * ny
-		im.SetTag(TagStripOffsets, TagTypeLong, make([]uint32, numTiles))
-		im.SetTag(TagStripByteCounts, TagTypeLong, make([]uint32, numTiles))
+			return nil, fmt.Errorf("missing TileWidth tag")
+		}
+		tileWidth, ok := im.Tag[TagTileWidth].Uint()
+		if !ok || tileWidth == 0 || tileWidth%16 != 0 {
+			return nil, fmt.Errorf("invalid TileWidth tag: must be a multiple of 16")
+		}
+		tileHeight, ok := im.Tag[TagTileLength].Uint()
+		if !ok || tileHeight == 0 || tileHeight%16 != 0 {
+			return nil, fmt.Errorf("invalid TileLength tag: must be a multiple of 16")
+		}
+		l.tiled = true
+		l.segmentWidth = int(tileWidth)
+		l.segmentHeight = int(tileHeight)
+		l.nx = (width + l.segmentWidth - 1) / l.segmentWidth
+		l.ny = (height + l.segmentHeight - 1) / l.segmentHeight
+		delete(im.Tag, TagStripOffsets)
+		delete(im.Tag, TagStripByteCounts)
+		delete(im.Tag, TagRowsPerStrip)
 	} else {
 		//
 		// Strip mode
 		//
+		rowsPerStrip := height
 		if im.Tag[TagRowsPerStrip] != nil {
 			uintRowsPerStrip, ok := im.Tag[TagRowsPerStrip].Uint()
 			if !ok || uintRowsPerStrip == 0 {
-				return fmt.Errorf("invalid RowsPerStrip tag")
+				return nil, fmt.Errorf("invalid RowsPerStrip tag")
+			}
+			if int(uintRowsPerStrip) < height {
+				rowsPerStrip = int(uintRowsPerStrip)
 			}
-			rowsPerStrip = int(uintRowsPerStrip)
-			numStrips = (int)(math.Ceil((float64)(height) / (float64)(rowsPerStrip)))
-			ny = numStrips
-			nx = 1
-		} else {
-			numStrips = 1
-			ny = 1
-			nx = 1
-			rowsPerStrip = height
 		}
-		im.SetTag(TagStripOffsets, TagTypeLong, make([]uint32, numStrips))
-		im.SetTag(TagStripByteCounts, TagTypeLong, make([]uint32, numStrips))
+		l.segmentWidth = width
+		l.segmentHeight = rowsPerStrip
+		l.nx = 1
+		l.ny = (height + rowsPerStrip - 1) / rowsPerStrip
+		delete(im.Tag, TagTileOffsets)
+		delete(im.Tag, TagTileByteCounts)
 	}
+	return l, nil
+}
 
-	//
-	// Write Header
-	//
-	if im.enc.offset == 0 {
-		if im.Header.Version == VersionClassicTIFF {
-			im.Header.OffsetFirstIFD = 8
-		} else {
-			im.Header.OffsetFirstIFD = 16
+// copySamples fills dst with the samples of buffer from the byte offset of the image data,
+// in the byte order of the file. Bytes of []uint8 are copied as they are.
+func copySamples(dst []byte, buffer interface{}, offset int, byteOrder binary.ByteOrder) {
+	switch buf := buffer.(type) {
+	case []uint8:
+		copy(dst, buf[offset:])
+	case []uint16:
+		src := buf[offset/2:]
+		for i := 0; i < len(dst)/2; i++ {
+			byteOrder.PutUint16(dst[2*i:], src[i])
+		}
+	}
+}
+
+// segmentData returns the uncompressed data of the segment at column ix and row iy of an image,
+// whose samples are in buffer. Tiles on the right and bottom edges are padded with zeros.
+//
+// Samples are converted to the byte order of the file one segment at a time,
+// and strips of []uint8 are returned without copying.
+func (l *segmentLayout) segmentData(buffer interface{}, byteOrder binary.ByteOrder, ix, iy int) []byte {
+	bytesPerPixel := l.samplesPerPixel * l.bytesPerSample
+	rect := l.segmentBounds(ix, iy)
+	if !l.tiled {
+		// Strips are not padded and are contiguous in buffer
+		start := rect.Min.Y * l.width * bytesPerPixel
+		end := rect.Max.Y * l.width * bytesPerPixel
+		if buf, ok := buffer.([]uint8); ok {
+			return buf[start:end]
 		}
+		seg := make([]byte, end-start)
+		copySamples(seg, buffer, start, byteOrder)
+		return seg
+	}
+
+	seg := make([]byte, l.segmentBytes())
+	count := rect.Dx() * bytesPerPixel
+	srcStride := l.width * bytesPerPixel
+	destStride := l.segmentWidth * bytesPerPixel
+	srcOffset := (rect.Min.Y*l.width + rect.Min.X) * bytesPerPixel
+	destOffset := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		copySamples(seg[destOffset:destOffset+count], buffer, srcOffset, byteOrder)
+		srcOffset += srcStride
+		destOffset += destStride
+	}
+	return seg
+}
 
-		buf := im.Header.encodeBytes()
-		_, err := im.enc.w.Write(buf)
+// writeSegments compresses and writes all segments of an image in order.
+//
+// Segments are compressed by the number of workers set with SetWorkers. The segments in buffer are indexed
+// from firstIndex in the image, and written reports the offset and byte count of each segment after it is written.
+func (im *Image) writeSegments(l *segmentLayout, buffer interface{}, firstIndex int, written func(index int, offset int64, byteCount int)) error {
+	numSegments := l.nx * l.ny
+	compress := func(index int) ([]byte, error) {
+		return im.compressSegment(l.segmentData(buffer, im.Header.ByteOrder, index%l.nx, index/l.nx))
+	}
+	write := func(index int, buf []byte) error {
+		offset, err := im.writeSegment(firstIndex+index, buf)
 		if err != nil {
 			return err
 		}
-		im.enc.offset += int64(len(buf))
+		written(firstIndex+index, offset, len(buf))
+		return nil
 	}
 
-	//
-	// Write IFD tags
-	//
-	buf, err := im.EncodeTags(im.enc.offset)
-	if err != nil {
-		return err
+	workers := im.enc.workers
+	if workers > numSegments {
+		workers = numSegments
 	}
-	_, err = im.enc.w.Write(buf)
-	if err != nil {
-		return err
+	if workers <= 1 {
+		for i := 0; i < numSegments; i++ {
+			buf, err := compress(i)
+			if err != nil {
+				return err
+			}
+			if err := write(i, buf); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	im.enc.offset += int64(len(buf))
-
-	//
-	// Strip mode
-	//
-	if numStrips > 0 {
-		stripOffsets := make([]uint32, numStrips)
-		stripByteCounts := make([]uint32, numStrips)
 
-		samplesPerStrip := rowsPerStrip * width * samplePerPixel
-		for iy := 0; iy < ny; iy++ {
-			offsetBuf := iy * samplesPerStrip
+	type result struct {
+		buf []byte
+		err error
+	}
+	results := make([]chan result, numSegments)
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	jobs := make(chan int)
+	done := make(chan struct{})
+	// Limit the number of compressed segments waiting to be written
+	pending := make(chan struct{}, 2*workers)
 
-			// Last strip may not be a full strip, and is not padded
-			if iy == ny-1 {
-				samplesPerStrip = (height - iy*rowsPerStrip) * width * samplePerPixel
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				buf, err := compress(i)
+				results[i] <- result{buf, err}
 			}
-
-			switch buf := buffer.(type) {
-			case []uint8:
-				offset, byteCount, err := im.encodeSegment(buf[offsetBuf : offsetBuf+samplesPerStrip])
-				if err != nil {
-					return err
-				}
-				stripOffsets[iy] = uint32(offset)
-				stripByteCounts[iy] = uint32(byteCount)
-			case []uint16:
-				if im.enc.Header.ByteOrder == binary.LittleEndian {
-					bufByte := (*(*[1 << 48]uint8)(unsafe.Pointer(&buf[0])))[offsetBuf*2 : (offsetBuf+samplesPerStrip)*2]
-					offset, byteCount, err := im.encodeSegment(bufByte)
-					if err != nil {
-						return err
-					}
-					stripOffsets[iy] = uint32(offset)
-					stripByteCounts[iy] = uint32(byteCount)
-				} else {
-					return UnsupportedError("big endian is not yet supported")
-				}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := 0; i < numSegments; i++ {
+			select {
+			case pending <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
 			}
 		}
+	}()
+	defer wg.Wait()
+	defer close(done)
 
-		im.SetTag(TagStripOffsets, TagTypeLong, stripOffsets)
-		im.SetTag(TagStripByteCounts, TagTypeLong, stripByteCounts)
-
-		// Current workaround is to overwrite all tags
-		buf, err := im.EncodeTags(im.Offset)
-		if err != nil {
-			return err
+	for i := 0; i < numSegments; i++ {
+		r := <-results[i]
+		<-pending
+		if r.err != nil {
+			return r.err
 		}
-		im.enc.w.Seek(im.Offset, io.SeekStart)
-		_, err = im.enc.w.Write(buf)
-		if err != nil {
+		if err := write(i, r.buf); err != nil {
 			return err
 		}
-		im.enc.w.Seek(im.enc.offset, io.SeekStart)
-	}
-
-	//
-	// Tile mode
-	//
-	if numTiles > 0 {
-		return UnsupportedError("tile mode is not yet supported")
 	}
-
 	return nil
 }
 
-// encodeSegment handles compression of segments. This is a temporary solution, will be replaced with a Writer.
-func (im *Image) encodeSegment(buf []byte) (offset int64, byteCount int, err error) {
-	offset = im.enc.offset
+// writeSegment writes compressed data of the segment at given index, and returns its offset.
+// It is safe for concurrent use.
+//
+// With LayoutCOG, a copy of the data is kept in memory until Close, and the returned offset is 0.
+func (im *Image) writeSegment(index int, buf []byte) (offset int64, err error) {
+	if im.segments != nil {
+		im.segments[index] = append([]byte(nil), buf...)
+		return 0, nil
+	}
+	return im.enc.write(buf)
+}
 
+// compressSegment returns compressed data of a segment according to the compression of the image.
+// It is safe for concurrent use.
+func (im *Image) compressSegment(buf []byte) ([]byte, error) {
 	compression := im.Compression()
 	switch compression {
 	case CompressionNone:
-		_, err = im.enc.w.Write(buf)
-		if err != nil {
-			byteCount = 0
-			return
-		}
-		im.enc.offset += int64(len(buf))
-		byteCount = len(buf)
-		return
+		return buf, nil
 	case CompressionDeflate:
-		w := zlib.NewWriter(im.enc.w)
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
 		_, err := w.Write(buf)
 		if err != nil {
 			w.Close()
-			return 0, 0, err
+			return nil, err
 		}
-		w.Close()
-		currentOffset, err := im.enc.w.Seek(0, io.SeekCurrent)
+		err = w.Close()
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
-		byteCount = int(currentOffset - offset)
-		im.enc.offset = currentOffset
-		return offset, byteCount, nil
-
+		return b.Bytes(), nil
+	case CompressionZstd:
+		e, err := im.enc.zstd()
+		if err != nil {
+			return nil, err
+		}
+		return e.EncodeAll(buf, nil), nil
 	default:
-		return 0, 0, UnsupportedError(fmt.Sprintf("compression %d is not supported", compression))
+		return nil, UnsupportedError(fmt.Sprintf("compression %d is not supported", compression))
 	}
 }
+
+// zstd returns a zstd encoder shared by all images of the Encoder.
+func (enc *Encoder) zstd() (*zstd.Encoder, error) {
+	enc.zstdOnce.Do(func() {
+		enc.zstdEncoder, enc.zstdErr = zstd.NewWriter(nil)
+	})
+	return enc.zstdEncoder, enc.zstdErr
+}
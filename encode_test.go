@@ -0,0 +1,742 @@
+package tiff_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+// writeSeeker is an in-memory io.WriteSeeker.
+type writeSeeker struct {
+	buf    []byte
+	offset int64
+}
+
+func (w *writeSeeker) Write(p []byte) (int, error) {
+	end := w.offset + int64(len(p))
+	if end > int64(len(w.buf)) {
+		w.buf = append(w.buf, make([]byte, end-int64(len(w.buf)))...)
+	}
+	copy(w.buf[w.offset:end], p)
+	w.offset = end
+	return len(p), nil
+}
+
+func (w *writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += w.offset
+	case io.SeekEnd:
+		offset += int64(len(w.buf))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	w.offset = offset
+	return offset, nil
+}
+
+func decodeFirst(t *testing.T, buf []byte) *tiff.Image {
+	d, err := tiff.NewDecoder(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := d.Iter()
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	return it.Image()
+}
+
+func TestEncode_ExtraSamples(t *testing.T) {
+	tests := []struct {
+		extraSamples int
+		colorModel   color.Model
+	}{
+		{tiff.ExtraSamplesAssociatedAlpha, color.RGBAModel},
+		{tiff.ExtraSamplesUnassociatedAlpha, color.NRGBAModel},
+		{tiff.ExtraSamplesUnspecified, color.RGBAModel},
+	}
+
+	data := []uint8{
+		10, 20, 30, 255, 40, 50, 60, 128,
+		70, 80, 90, 64, 0, 0, 0, 0,
+	}
+	for _, test := range tests {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		im := enc.NewImage()
+		im.SetWidthHeight(2, 2)
+		im.SetPixelFormat(tiff.PhotometricRGB, 4, []int{8, 8, 8, 8}, test.extraSamples)
+		if err := im.EncodeImage(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		im = decodeFirst(t, w.buf)
+		if got := im.ExtraSamples(); !reflect.DeepEqual(got, []int{test.extraSamples}) {
+			t.Errorf("ExtraSamples() = %v, want [%d]", got, test.extraSamples)
+		}
+		if got := im.ColorModel(); got != test.colorModel {
+			t.Errorf("ExtraSamples %d: unexpected ColorModel", test.extraSamples)
+		}
+		buf := make([]uint8, len(data))
+		if err := im.DecodeImage(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf, data) {
+			t.Errorf("decoded %v, want %v", buf, data)
+		}
+	}
+
+	// One ExtraSamples value is required for each sample after the color components
+	for _, test := range []struct {
+		samplesPerPixel int
+		extraSamples    []int
+	}{
+		{4, nil},
+		{5, []int{tiff.ExtraSamplesUnassociatedAlpha}},
+		{4, []int{tiff.ExtraSamplesUnspecified, tiff.ExtraSamplesUnspecified}},
+		{2, nil},
+	} {
+		im := tiff.NewEncoder(&writeSeeker{}).NewImage()
+		im.SetWidthHeight(2, 2)
+		bitsPerSample := make([]int, test.samplesPerPixel)
+		for i := range bitsPerSample {
+			bitsPerSample[i] = 8
+		}
+		im.SetPixelFormat(tiff.PhotometricRGB, test.samplesPerPixel, bitsPerSample, test.extraSamples...)
+		if err := im.EncodeImage(make([]uint8, 2*2*test.samplesPerPixel)); err == nil {
+			t.Errorf("no error for %d samples per pixel with ExtraSamples %v", test.samplesPerPixel, test.extraSamples)
+		}
+	}
+}
+
+func TestEncode_Workers(t *testing.T) {
+	const width, height = 100, 70
+	data := make([]uint8, width*height*3)
+	for i := range data {
+		data[i] = uint8(i / 7)
+	}
+
+	for _, tiled := range []bool{false, true} {
+		for _, compression := range []int{tiff.CompressionNone, tiff.CompressionDeflate, tiff.CompressionZstd} {
+			w := &writeSeeker{}
+			enc := tiff.NewEncoder(w)
+			enc.SetWorkers(4)
+			// Encode two pages to verify the chain of IFDs
+			for page := 0; page < 2; page++ {
+				im := enc.NewImage()
+				im.SetWidthHeight(width, height)
+				im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+				im.SetCompression(compression)
+				if tiled {
+					im.SetTileWidthHeight(32, 16)
+				} else {
+					im.SetRowsPerStrip(3)
+				}
+				if err := im.EncodeImage(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ims, err := d.Iter().All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ims) != 2 {
+				t.Fatalf("found %d images, want 2", len(ims))
+			}
+			for _, im := range ims {
+				if tiled != (im.NumTiles() > 0) {
+					t.Fatalf("tiled = %v, found %d tiles", tiled, im.NumTiles())
+				}
+				buf := make([]uint8, len(data))
+				if err := im.DecodeImage(buf); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(buf, data) {
+					t.Fatalf("tiled = %v, compression %d: data inconsistent with source", tiled, compression)
+				}
+			}
+		}
+	}
+}
+
+func TestRowWriter(t *testing.T) {
+	const width, height = 50, 45
+	data := make([]uint16, width*height*3)
+	for i := range data {
+		data[i] = uint16(i)
+	}
+
+	for _, tiled := range []bool{false, true} {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{16, 16, 16})
+		im.SetCompression(tiff.CompressionDeflate)
+		if tiled {
+			im.SetTileWidthHeight(16, 16)
+		} else {
+			im.SetRowsPerStrip(4)
+		}
+		rw, err := im.NewRowWriter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.WriteRows(make([]float32, width*3)); err == nil {
+			t.Errorf("no error for rows of []float32")
+		}
+		// Write rows in chunks not aligned with strips or tiles
+		samplesPerRow := width * 3
+		for y := 0; y < height; y += 7 {
+			end := y + 7
+			if end > height {
+				end = height
+			}
+			if err := rw.WriteRows(data[y*samplesPerRow : end*samplesPerRow]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]uint16, len(data))
+		if err := decodeFirst(t, w.buf).DecodeImage(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf, data) {
+			t.Fatalf("tiled = %v: data inconsistent with source", tiled)
+		}
+	}
+
+	im := tiff.NewEncoder(&writeSeeker{}).NewImage()
+	im.SetWidthHeight(width, height)
+	im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{16, 16, 16})
+	im.SetTileWidthHeight(16, 16)
+	im.SetOverviews(&tiff.OverviewOptions{})
+	if _, err := im.NewRowWriter(); err == nil {
+		t.Errorf("no error for RowWriter with overviews")
+	}
+	if _, err := im.NewTileWriter(); err == nil {
+		t.Errorf("no error for TileWriter with overviews")
+	}
+}
+
+func TestTileWriter(t *testing.T) {
+	const width, height = 50, 45
+	const tileWidth, tileHeight = 16, 16
+	data := make([]uint8, width*height*3)
+	for i := range data {
+		data[i] = uint8(i / 5)
+	}
+
+	for _, sparse := range []bool{false, true} {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+		im.SetCompression(tiff.CompressionDeflate)
+		im.SetTileWidthHeight(tileWidth, tileHeight)
+		tw, err := im.NewTileWriter()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Write tiles in reverse order from concurrent goroutines, as cropped tiles
+		const nx, ny = 4, 3
+		var wg sync.WaitGroup
+		errs := make(chan error, nx*ny)
+		for i := nx*ny - 1; i >= 0; i-- {
+			ix, iy := i%nx, i/nx
+			if sparse && ix == 1 && iy == 1 {
+				continue
+			}
+			x0, y0 := ix*tileWidth, iy*tileHeight
+			x1, y1 := x0+tileWidth, y0+tileHeight
+			if x1 > width {
+				x1 = width
+			}
+			if y1 > height {
+				y1 = height
+			}
+			tile := make([]uint8, 0, (x1-x0)*(y1-y0)*3)
+			for y := y0; y < y1; y++ {
+				tile = append(tile, data[(y*width+x0)*3:(y*width+x1)*3]...)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- tw.WriteTile(ix, iy, tile)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.WriteTile(0, 0, make([]uint8, tileWidth*tileHeight*3)); err == nil {
+			t.Error("expecting error when writing a tile twice")
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		im = decodeFirst(t, w.buf)
+		byteCounts, _ := im.Tag[tiff.TagTileByteCounts].UintSlice()
+		offsets, _ := im.Tag[tiff.TagTileOffsets].UintSlice()
+		if len(byteCounts) != nx*ny || len(offsets) != nx*ny {
+			t.Fatalf("found %d tiles, want %d", len(byteCounts), nx*ny)
+		}
+		for i := range byteCounts {
+			missing := sparse && i == nx+1
+			if missing != (byteCounts[i] == 0 && offsets[i] == 0) {
+				t.Errorf("sparse = %v: tile %d has offset %d and byte count %d", sparse, i, offsets[i], byteCounts[i])
+			}
+		}
+		if sparse {
+			continue
+		}
+		buf := make([]uint8, len(data))
+		if err := im.DecodeImage(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf, data) {
+			t.Fatal("data inconsistent with source")
+		}
+	}
+}
+
+// headWriteSeeker is an io.WriteSeeker keeping only the bytes written before limit,
+// to encode files larger than memory.
+type headWriteSeeker struct {
+	head   []byte
+	offset int64
+}
+
+func (w *headWriteSeeker) Write(p []byte) (int, error) {
+	if w.offset < int64(len(w.head)) {
+		copy(w.head[w.offset:], p)
+	}
+	w.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (w *headWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("unsupported whence")
+	}
+	w.offset = offset
+	return offset, nil
+}
+
+func TestEncode_BigTIFF(t *testing.T) {
+	// Small BigTIFF files use Long8 offsets, and VersionAuto chooses ClassicTIFF for small files
+	tests := []struct {
+		version, want tiff.Version
+		tagType       tiff.TagType
+	}{
+		{tiff.VersionBigTIFF, tiff.VersionBigTIFF, tiff.TagTypeLong8},
+		{tiff.VersionAuto, tiff.VersionClassicTIFF, tiff.TagTypeLong},
+	}
+	for _, test := range tests {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		enc.SetVersion(test.version)
+		im := enc.NewImage()
+		im.SetWidthHeight(3, 2)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		if err := im.EncodeImage([]uint8{1, 2, 3, 4, 5, 6}); err != nil {
+			t.Fatal(err)
+		}
+		im = decodeFirst(t, w.buf)
+		if im.Header.Version != test.want {
+			t.Fatalf("%v: version %v, want %v", test.version, im.Header.Version, test.want)
+		}
+		for _, id := range []tiff.TagID{tiff.TagStripOffsets, tiff.TagStripByteCounts} {
+			if im.Tag[id].Type != test.tagType {
+				t.Errorf("%v: %v is of type %v, want %v", test.version, id, im.Tag[id].Type, test.tagType)
+			}
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("skipping encoding of files larger than 4 GiB in short mode")
+	}
+	// Images larger than 4 GiB
+	const width, height, rowsPerStrip = 1 << 16, 1<<16 + 16, 256
+	rows := make([]uint8, width*rowsPerStrip)
+	encodeLarge := func(version tiff.Version) (*headWriteSeeker, error) {
+		w := &headWriteSeeker{head: make([]byte, 1<<16)}
+		enc := tiff.NewEncoder(w)
+		enc.SetVersion(version)
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		im.SetRowsPerStrip(rowsPerStrip)
+		rw, err := im.NewRowWriter()
+		if err != nil {
+			return nil, err
+		}
+		for y := 0; y < height; y += rowsPerStrip {
+			n := rowsPerStrip
+			if y+n > height {
+				n = height - y
+			}
+			if err := rw.WriteRows(rows[:n*width]); err != nil {
+				return nil, err
+			}
+		}
+		return w, rw.Close()
+	}
+
+	if _, err := encodeLarge(tiff.VersionClassicTIFF); err == nil || !strings.Contains(err.Error(), "use VersionBigTIFF") {
+		t.Errorf("expecting ClassicTIFF overflow error, got %v", err)
+	}
+
+	w2, err := encodeLarge(tiff.VersionAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := decodeFirst(t, w2.head)
+	if im.Header.Version != tiff.VersionBigTIFF {
+		t.Fatalf("version %v, want BigTIFF", im.Header.Version)
+	}
+	offsets, _ := im.Tag[tiff.TagStripOffsets].UintSlice()
+	if last := offsets[len(offsets)-1]; last <= 1<<32 {
+		t.Errorf("offset of last strip %d, want more than 4 GiB", last)
+	}
+}
+
+func TestImage_EncodeTags_VersionAuto(t *testing.T) {
+	// The version of the header must be resolved before tags are encoded
+	im := tiff.NewEncoder(&writeSeeker{}).NewImage()
+	im.Header = &tiff.Header{ByteOrder: binary.LittleEndian, Version: tiff.VersionAuto}
+	im.SetWidthHeight(2, 2)
+	if _, err := im.EncodeTags(8); err == nil {
+		t.Errorf("no error for VersionAuto")
+	}
+}
+
+func TestEncode_BigEndian(t *testing.T) {
+	const width, height = 37, 21
+	data := make([]uint16, width*height*3)
+	for i := range data {
+		data[i] = uint16(i * 251)
+	}
+
+	for _, tiled := range []bool{false, true} {
+		for _, compression := range []int{tiff.CompressionNone, tiff.CompressionDeflate} {
+			w := &writeSeeker{}
+			enc := tiff.NewEncoder(w)
+			enc.SetByteOrder(binary.BigEndian)
+			im := enc.NewImage()
+			im.SetWidthHeight(width, height)
+			im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{16, 16, 16})
+			im.SetCompression(compression)
+			if tiled {
+				im.SetTileWidthHeight(16, 16)
+			} else {
+				im.SetRowsPerStrip(5)
+			}
+			if err := im.EncodeImage(data); err != nil {
+				t.Fatal(err)
+			}
+			if string(w.buf[:4]) != "MM\x00*" {
+				t.Fatalf("unexpected header % x", w.buf[:4])
+			}
+
+			im = decodeFirst(t, w.buf)
+			if compression == tiff.CompressionNone && !tiled {
+				raw := make([]byte, 4)
+				if _, err := im.RawStripReader(0).Read(raw); err != nil {
+					t.Fatal(err)
+				}
+				if got := []uint16{binary.BigEndian.Uint16(raw), binary.BigEndian.Uint16(raw[2:])}; !reflect.DeepEqual(got, data[:2]) {
+					t.Errorf("raw samples %v, want %v", got, data[:2])
+				}
+			}
+			buf := make([]uint16, len(data))
+			if err := im.DecodeImage(buf); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(buf, data) {
+				t.Fatalf("tiled = %v, compression %d: data inconsistent with source", tiled, compression)
+			}
+		}
+	}
+}
+
+func TestEncode_SampleBytes(t *testing.T) {
+	data := []uint16{0x0102, 0xa0b0, 0xfffe, 0x0010, 0x7f80, 0x0001}
+	for _, byteOrder := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		enc.SetByteOrder(byteOrder)
+		im := enc.NewImage()
+		im.SetWidthHeight(2, 1)
+		im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{16, 16, 16})
+		if err := im.EncodeImage(data); err != nil {
+			t.Fatal(err)
+		}
+
+		want := make([]byte, 2*len(data))
+		for i, v := range data {
+			byteOrder.PutUint16(want[2*i:], v)
+		}
+		raw := make([]byte, len(want))
+		if _, err := io.ReadFull(decodeFirst(t, w.buf).RawStripReader(0), raw); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, want) {
+			t.Errorf("%v: samples % x, want % x", byteOrder, raw, want)
+		}
+	}
+}
+
+func TestStreamEncoder(t *testing.T) {
+	const width, height = 40, 30
+	data := make([]uint8, width*height*3)
+	for i := range data {
+		data[i] = uint8(i / 3)
+	}
+
+	// bytes.Buffer cannot seek
+	var stream bytes.Buffer
+	enc := tiff.NewStreamEncoder(&stream)
+	for page := 0; page < 5; page++ {
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+		switch page {
+		case 0:
+			im.SetCompression(tiff.CompressionDeflate)
+			im.SetRowsPerStrip(7)
+		case 1:
+			im.SetCompression(tiff.CompressionDeflate)
+			im.SetTileWidthHeight(16, 16)
+		case 2:
+			im.SetTileWidthHeight(16, 16)
+			im.SetOverviews(&tiff.OverviewOptions{Levels: 1, SubIFDs: true})
+		case 3:
+			im.SetOverviews(&tiff.OverviewOptions{Levels: 1})
+		}
+		if page < 4 {
+			if err := im.EncodeImage(data); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		rw, err := im.NewRowWriter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.WriteRows(data); err != nil {
+			t.Fatal(err)
+		}
+		// Uncompressed image data are written before the image is complete
+		if n := stream.Len(); n < len(data) {
+			t.Errorf("%d bytes written before closing the RowWriter, want more than %d", n, len(data))
+		}
+		if err := rw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(stream.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pyramids, err := d.Pyramids()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pyramids) != 5 {
+		t.Fatalf("found %d pyramids, want 5", len(pyramids))
+	}
+	for i, pyramid := range pyramids {
+		buf := make([]uint8, len(data))
+		if err := pyramid.Levels[0].DecodeImage(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf, data) {
+			t.Fatalf("image %d: data inconsistent with source", i)
+		}
+		if want := map[int]int{2: 2, 3: 2}[i]; want != 0 && len(pyramid.Levels) != want {
+			t.Errorf("image %d has %d levels, want %d", i, len(pyramid.Levels), want)
+		}
+	}
+
+	// Only compressed image data are kept in memory, besides the IFD
+	rng := rand.New(rand.NewSource(1))
+	for i := range data {
+		data[i] = uint8(rng.Intn(256))
+	}
+	for _, compression := range []int{tiff.CompressionNone, tiff.CompressionDeflate} {
+		stream.Reset()
+		enc := tiff.NewStreamEncoder(&stream)
+		enc.SetStreamBufferSize(1024)
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{8, 8, 8})
+		im.SetCompression(compression)
+		err := im.EncodeImage(data)
+		if compression == tiff.CompressionNone && err != nil {
+			t.Errorf("uncompressed image: %v", err)
+		}
+		if compression != tiff.CompressionNone && (err == nil || !strings.Contains(err.Error(), "SetStreamBufferSize")) {
+			t.Errorf("expecting stream buffer error, got %v", err)
+		}
+	}
+}
+
+// writerAt is an in-memory io.WriterAt safe for concurrent use.
+type writerAt struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *writerAt) WriteAt(p []byte, offset int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	end := offset + int64(len(p))
+	if end > int64(len(w.buf)) {
+		w.buf = append(w.buf, make([]byte, end-int64(len(w.buf)))...)
+	}
+	copy(w.buf[offset:end], p)
+	return len(p), nil
+}
+
+func TestEncoderAt(t *testing.T) {
+	const width, height = 64, 48
+	data := make([]uint8, width*height)
+	for i := range data {
+		data[i] = uint8(i / 11)
+	}
+
+	for _, version := range []tiff.Version{tiff.VersionClassicTIFF, tiff.VersionBigTIFF} {
+		w := &writerAt{}
+		enc := tiff.NewEncoderAt(w)
+		enc.SetVersion(version)
+
+		// A stripped image, followed by two tiled images written concurrently
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		im.SetRowsPerStrip(5)
+		if err := im.EncodeImage(data); err != nil {
+			t.Fatal(err)
+		}
+		var tws []*tiff.TileWriter
+		for i := 0; i < 2; i++ {
+			im := enc.NewImage()
+			im.SetWidthHeight(width, height)
+			im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+			im.SetCompression(tiff.CompressionZstd)
+			im.SetTileWidthHeight(16, 16)
+			tw, err := im.NewTileWriter()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tws = append(tws, tw)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, 2*4*3)
+		for iy := 0; iy < 3; iy++ {
+			for ix := 0; ix < 4; ix++ {
+				tile := make([]uint8, 0, 16*16)
+				for y := iy * 16; y < iy*16+16; y++ {
+					tile = append(tile, data[y*width+ix*16:y*width+ix*16+16]...)
+				}
+				for _, tw := range tws {
+					wg.Add(1)
+					go func(tw *tiff.TileWriter, ix, iy int) {
+						defer wg.Done()
+						errs <- tw.WriteTile(ix, iy, tile)
+					}(tw, ix, iy)
+				}
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tw := range tws {
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ims, err := d.Iter().All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ims) != 3 {
+			t.Fatalf("%v: found %d images, want 3", version, len(ims))
+		}
+		for i, im := range ims {
+			// IFDs are written after all image data
+			offsetTagID := tiff.TagStripOffsets
+			if i > 0 {
+				offsetTagID = tiff.TagTileOffsets
+			}
+			offsets, _ := im.Tag[offsetTagID].UintSlice()
+			for _, offset := range offsets {
+				if int64(offset) >= ims[0].Offset {
+					t.Fatalf("%v: image data at offset %d after the first IFD at %d", version, offset, ims[0].Offset)
+				}
+			}
+			buf := make([]uint8, len(data))
+			if err := im.DecodeImage(buf); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(buf, data) {
+				t.Fatalf("%v: image %d: data inconsistent with source", version, i)
+			}
+		}
+	}
+}
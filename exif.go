@@ -0,0 +1,163 @@
+package tiff
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExifInfo is the camera metadata of an image decoded from its Exif IFD.
+//
+// Fields of missing or invalid tags have their zero value.
+type ExifInfo struct {
+	Make  string // Camera manufacturer, from the IFD of the image
+	Model string // Camera model, from the IFD of the image
+
+	ExposureTime          time.Duration
+	FNumber               float64
+	ISO                   int
+	ExposureBias          float64 // In EV
+	ExposureProgram       int
+	MeteringMode          int
+	Flash                 int // Flash status bits, whose bit 0 is set if the flash fired
+	WhiteBalance          int
+	FocalLength           float64 // In millimeters
+	FocalLengthIn35mmFilm int     // In millimeters
+
+	LensMake         string
+	LensModel        string
+	LensSerialNumber string
+	BodySerialNumber string
+
+	// Dates combine DateTime..., SubsecTime... and OffsetTime... tags. Without OffsetTime..., the time zone
+	// is unknown, and the date is in UTC.
+	DateTimeOriginal  time.Time
+	DateTimeDigitized time.Time
+
+	PixelXDimension int
+	PixelYDimension int
+	ColorSpace      int
+}
+
+// FlashFired reports whether the flash fired.
+func (info *ExifInfo) FlashFired() bool {
+	return info.Flash&1 != 0
+}
+
+// ExifInfo decodes the Exif IFD of the image, with the camera make and model of the image.
+func (im *Image) ExifInfo() ExifInfo {
+	info := im.Exif.Decode()
+	info.Make, _ = im.Tag[TagMake].String()
+	info.Model, _ = im.Tag[TagModel].String()
+	info.Make = strings.TrimSpace(info.Make)
+	info.Model = strings.TrimSpace(info.Model)
+	return info
+}
+
+// Decode returns the values of common Exif tags. A nil ExifIFD decodes as zero values.
+func (dir *ExifIFD) Decode() ExifInfo {
+	var info ExifInfo
+	if dir == nil {
+		return info
+	}
+	tag := func(id ExifTagID) *TagValue {
+		return dir.Tag[id].Value()
+	}
+	number := func(id ExifTagID) int {
+		v, _ := tag(id).UintSlice()
+		if len(v) == 0 {
+			return 0
+		}
+		return int(v[0])
+	}
+	str := func(id ExifTagID) string {
+		v, _ := tag(id).String()
+		return strings.TrimSpace(v)
+	}
+
+	if r, ok := tag(ExifTagExposureTime).Rational(); ok && r[1] != 0 {
+		info.ExposureTime = time.Duration(math.Round(r.Float64() * float64(time.Second)))
+	} else if r, ok := tag(ExifTagShutterSpeedValue).SRational(); ok && r[1] != 0 {
+		// APEX time value
+		info.ExposureTime = time.Duration(math.Round(math.Exp2(-r.Float64()) * float64(time.Second)))
+	}
+	if r, ok := tag(ExifTagFNumber).Rational(); ok && r[1] != 0 {
+		info.FNumber = r.Float64()
+	} else if r, ok := tag(ExifTagApertureValue).Rational(); ok && r[1] != 0 {
+		// APEX aperture value
+		info.FNumber = math.Exp2(r.Float64() / 2)
+	}
+	info.ISO = number(ExifTagISOSpeedRatings)
+	if r, ok := tag(ExifTagExposureBiasValue).SRational(); ok && r[1] != 0 {
+		info.ExposureBias = r.Float64()
+	}
+	info.ExposureProgram = number(ExifTagExposureProgram)
+	info.MeteringMode = number(ExifTagMeteringMode)
+	info.Flash = number(ExifTagFlash)
+	info.WhiteBalance = number(ExifTagWhiteBalance)
+	if r, ok := tag(ExifTagFocalLength).Rational(); ok && r[1] != 0 {
+		info.FocalLength = r.Float64()
+	}
+	info.FocalLengthIn35mmFilm = number(ExifTagFocalLengthIn35mmFilm)
+
+	info.LensMake = str(ExifTagLensMake)
+	info.LensModel = str(ExifTagLensModel)
+	info.LensSerialNumber = str(ExifTagLensSerialNumber)
+	info.BodySerialNumber = str(ExifTagBodySerialNumber)
+
+	info.DateTimeOriginal = exifTime(str(ExifTagDateTimeOriginal), str(ExifTagSubsecTimeOriginal), str(ExifTagOffsetTimeOriginal))
+	info.DateTimeDigitized = exifTime(str(ExifTagDateTimeDigitized), str(ExifTagSubsecTimeDigitized), str(ExifTagOffsetTimeDigitized))
+
+	info.PixelXDimension = number(ExifTagPixelXDimension)
+	info.PixelYDimension = number(ExifTagPixelYDimension)
+	info.ColorSpace = number(ExifTagColorSpace)
+	return info
+}
+
+// exifTime combines an Exif date "YYYY:MM:DD HH:MM:SS", its fraction of second as digits,
+// and its offset from UTC as "+HH:MM". It returns the zero time if the date is invalid.
+func exifTime(date string, subsec string, offset string) time.Time {
+	t, err := time.Parse("2006:01:02 15:04:05", date)
+	if err != nil {
+		return time.Time{}
+	}
+	if fraction, err := strconv.ParseFloat("0."+subsec, 64); err == nil && strings.Trim(subsec, "0123456789") == "" {
+		t = t.Add(time.Duration(math.Round(fraction * float64(time.Second))))
+	}
+	if zone, err := time.Parse("-07:00", offset); err == nil {
+		_, seconds := zone.Zone()
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+			time.FixedZone(offset, seconds))
+	}
+	return t
+}
+
+// SetExifTag sets a tag of the Exif IFD, which is written after the IFD of the image.
+//
+// ExifVersion is set to 0232 when the Exif IFD is created.
+func (im *Image) SetExifTag(id ExifTagID, tagType TagType, value interface{}) error {
+	if im.Exif == nil {
+		im.Exif = &ExifIFD{Header: im.Header}
+	}
+	if im.Exif.Tag == nil {
+		im.Exif.Tag = make(map[ExifTagID]*ExifTag)
+		if id != ExifTagExifVersion {
+			if err := im.SetExifTag(ExifTagExifVersion, TagTypeUndefined, []byte("0232")); err != nil {
+				return err
+			}
+		}
+	}
+	tag, err := NewTag(TagID(id), tagType, value, im.Header)
+	if err != nil {
+		return err
+	}
+	im.Exif.Tag[id] = &ExifTag{
+		ID:     id,
+		Type:   tag.Type,
+		Count:  tag.Count,
+		Data:   tag.Data,
+		Header: im.Header,
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package tiff_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestImage_ExifInfo(t *testing.T) {
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	im.SetTag(tiff.TagMake, tiff.TagTypeASCII, "NIKON CORPORATION")
+	im.SetTag(tiff.TagModel, tiff.TagTypeASCII, "NIKON Z 6 ")
+	for _, err := range []error{
+		im.SetExifTag(tiff.ExifTagExposureTime, tiff.TagTypeRational, tiff.NewRational(1, 250)),
+		im.SetExifTag(tiff.ExifTagFNumber, tiff.TagTypeRational, tiff.NewRational(56, 10)),
+		im.SetExifTag(tiff.ExifTagISOSpeedRatings, tiff.TagTypeShort, uint16(400)),
+		im.SetExifTag(tiff.ExifTagExposureBiasValue, tiff.TagTypeSRational, tiff.NewSRational(-2, 3)),
+		im.SetExifTag(tiff.ExifTagMeteringMode, tiff.TagTypeShort, uint16(5)),
+		im.SetExifTag(tiff.ExifTagFlash, tiff.TagTypeShort, uint16(0x19)),
+		im.SetExifTag(tiff.ExifTagFocalLength, tiff.TagTypeRational, tiff.NewRational(500, 10)),
+		im.SetExifTag(tiff.ExifTagLensModel, tiff.TagTypeASCII, "NIKKOR Z 50mm f/1.8 S"),
+		im.SetExifTag(tiff.ExifTagDateTimeOriginal, tiff.TagTypeASCII, "2021:07:14 10:30:15"),
+		im.SetExifTag(tiff.ExifTagSubsecTimeOriginal, tiff.TagTypeASCII, "25"),
+		im.SetExifTag(tiff.ExifTagOffsetTimeOriginal, tiff.TagTypeASCII, "+02:00"),
+		im.SetExifTag(tiff.ExifTagDateTimeDigitized, tiff.TagTypeASCII, "2021:07:14 10:30:15"),
+		im.SetGPSAltitude(100),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := im.EncodeImage(make([]uint8, 2*2)); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := ims[0].Exif.Tag[tiff.ExifTagExifVersion].Value().Bytes(); !ok || string(v) != "0232" {
+		t.Errorf("ExifVersion = %q, %v", v, ok)
+	}
+	if v, ok := ims[0].GPS.Altitude(); !ok || v != 100 {
+		t.Errorf("GPS Altitude = %v, %v", v, ok)
+	}
+	info := ims[0].ExifInfo()
+	want := tiff.ExifInfo{
+		Make:              "NIKON CORPORATION",
+		Model:             "NIKON Z 6",
+		ExposureTime:      4 * time.Millisecond,
+		FNumber:           5.6,
+		ISO:               400,
+		ExposureBias:      -2.0 / 3,
+		MeteringMode:      5,
+		Flash:             0x19,
+		FocalLength:       50,
+		LensModel:         "NIKKOR Z 50mm f/1.8 S",
+		DateTimeOriginal:  time.Date(2021, 7, 14, 10, 30, 15, 250e6, time.FixedZone("+02:00", 2*3600)),
+		DateTimeDigitized: time.Date(2021, 7, 14, 10, 30, 15, 0, time.UTC),
+	}
+	if !info.DateTimeOriginal.Equal(want.DateTimeOriginal) || info.DateTimeOriginal.Format(time.RFC3339) != "2021-07-14T10:30:15+02:00" {
+		t.Errorf("DateTimeOriginal = %v, want %v", info.DateTimeOriginal, want.DateTimeOriginal)
+	}
+	if !info.DateTimeDigitized.Equal(want.DateTimeDigitized) {
+		t.Errorf("DateTimeDigitized = %v, want %v", info.DateTimeDigitized, want.DateTimeDigitized)
+	}
+	info.DateTimeOriginal, want.DateTimeOriginal = time.Time{}, time.Time{}
+	info.DateTimeDigitized, want.DateTimeDigitized = time.Time{}, time.Time{}
+	if info != want {
+		t.Errorf("ExifInfo = %+v, want %+v", info, want)
+	}
+	if !info.FlashFired() {
+		t.Errorf("flash did not fire")
+	}
+
+	var missing *tiff.ExifIFD
+	if info := missing.Decode(); info != (tiff.ExifInfo{}) {
+		t.Errorf("Decode of nil ExifIFD = %+v", info)
+	}
+}
@@ -0,0 +1,199 @@
+package tiff
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GDALDataset is the sample of GDAL metadata items applying to the whole image instead of one band.
+const GDALDataset = -1
+
+// GDALMetadataItem is a metadata item stored by GDAL in the GDAL_METADATA tag.
+type GDALMetadataItem struct {
+	Name   string
+	Value  string
+	Domain string // Metadata domain, empty for the default domain
+	Sample int    // Band of the item starting from 0, or GDALDataset
+	Role   string // Band property stored as an item, e.g. "description", "scale", "offset" or "unittype"
+}
+
+// GDALMetadata are the metadata items stored by GDAL in the GDAL_METADATA tag,
+// such as band statistics, descriptions, scale and offset.
+type GDALMetadata struct {
+	Items []GDALMetadataItem
+}
+
+// gdalMetadataXML is the XML document of the GDAL_METADATA tag.
+type gdalMetadataXML struct {
+	XMLName xml.Name      `xml:"GDALMetadata"`
+	Items   []gdalItemXML `xml:"Item"`
+}
+
+// gdalItemXML is an Item element of the GDAL_METADATA tag.
+type gdalItemXML struct {
+	Name   string `xml:"name,attr"`
+	Domain string `xml:"domain,attr,omitempty"`
+	Sample string `xml:"sample,attr,omitempty"`
+	Role   string `xml:"role,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+// GDALMetadata parses the XML document of the GDAL_METADATA tag.
+//
+// It returns (nil, nil) when the image has no GDAL_METADATA tag.
+func (im *Image) GDALMetadata() (*GDALMetadata, error) {
+	s, ok := im.Tag[TagGDALMetadata].String()
+	if !ok {
+		return nil, nil
+	}
+	var doc gdalMetadataXML
+	if err := xml.Unmarshal([]byte(s), &doc); err != nil {
+		return nil, FormatError(fmt.Sprintf("GDAL_METADATA: %v", err))
+	}
+	m := &GDALMetadata{Items: make([]GDALMetadataItem, len(doc.Items))}
+	for i, item := range doc.Items {
+		sample := GDALDataset
+		if item.Sample != "" {
+			var err error
+			sample, err = strconv.Atoi(item.Sample)
+			if err != nil || sample < 0 {
+				return nil, FormatError(fmt.Sprintf("GDAL_METADATA: invalid sample %q", item.Sample))
+			}
+		}
+		m.Items[i] = GDALMetadataItem{
+			Name:   item.Name,
+			Value:  item.Value,
+			Domain: item.Domain,
+			Sample: sample,
+			Role:   item.Role,
+		}
+	}
+	return m, nil
+}
+
+// SetGDALMetadata sets the GDAL_METADATA tag of the image to the XML document of m.
+func (im *Image) SetGDALMetadata(m *GDALMetadata) error {
+	var doc gdalMetadataXML
+	doc.Items = make([]gdalItemXML, len(m.Items))
+	for i, item := range m.Items {
+		doc.Items[i].Name = item.Name
+		doc.Items[i].Value = item.Value
+		doc.Items[i].Domain = item.Domain
+		doc.Items[i].Role = item.Role
+		if item.Sample != GDALDataset {
+			doc.Items[i].Sample = strconv.Itoa(item.Sample)
+		}
+	}
+	buf, err := xml.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return im.SetTag(TagGDALMetadata, TagTypeASCII, string(buf)+"\n")
+}
+
+// Get returns the value of the item with given domain, sample and name.
+func (m *GDALMetadata) Get(domain string, sample int, name string) (value string, ok bool) {
+	if m == nil {
+		return "", false
+	}
+	for _, item := range m.Items {
+		if item.Domain == domain && item.Sample == sample && item.Name == name {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the item with given domain, sample and name, adding the item if needed.
+func (m *GDALMetadata) Set(domain string, sample int, name string, value string) {
+	for i, item := range m.Items {
+		if item.Domain == domain && item.Sample == sample && item.Name == name {
+			m.Items[i].Value = value
+			return
+		}
+	}
+	item := GDALMetadataItem{Name: name, Value: value, Domain: domain, Sample: sample}
+	// Band properties are identified by their role
+	switch name {
+	case "DESCRIPTION", "SCALE", "OFFSET", "UNITTYPE":
+		if sample != GDALDataset && domain == "" {
+			item.Role = strings.ToLower(name)
+		}
+	}
+	m.Items = append(m.Items, item)
+}
+
+// Domain returns the items of a domain and sample as a map from names to values.
+func (m *GDALMetadata) Domain(domain string, sample int) map[string]string {
+	values := make(map[string]string)
+	if m == nil {
+		return values
+	}
+	for _, item := range m.Items {
+		if item.Domain == domain && item.Sample == sample {
+			values[item.Name] = item.Value
+		}
+	}
+	return values
+}
+
+// Float returns the value of an item of the default domain as float64, such as STATISTICS_MEAN.
+func (m *GDALMetadata) Float(sample int, name string) (value float64, ok bool) {
+	s, ok := m.Get("", sample, name)
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// Description returns the description of a band.
+func (m *GDALMetadata) Description(sample int) (string, bool) {
+	return m.Get("", sample, "DESCRIPTION")
+}
+
+// ScaleOffset returns the scale and offset of a band, converting its samples to physical values
+// as value = sample*scale + offset. It returns (1, 0, false) when neither is set.
+func (m *GDALMetadata) ScaleOffset(sample int) (scale float64, offset float64, ok bool) {
+	scale, okScale := m.Float(sample, "SCALE")
+	offset, okOffset := m.Float(sample, "OFFSET")
+	if !okScale {
+		scale = 1
+	}
+	return scale, offset, okScale || okOffset
+}
+
+// GDALNoData returns the value of samples marking missing data, from the GDAL_NODATA tag.
+func (im *Image) GDALNoData() (value float64, ok bool) {
+	s, ok := im.Tag[TagGDALNoData].String()
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// SetGDALNoData sets the GDAL_NODATA tag, which applies to all bands of the image.
+func (im *Image) SetGDALNoData(value float64) error {
+	var s string
+	switch {
+	case math.IsNaN(value):
+		s = "nan"
+	case math.IsInf(value, 1):
+		s = "inf"
+	case math.IsInf(value, -1):
+		s = "-inf"
+	default:
+		s = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return im.SetTag(TagGDALNoData, TagTypeASCII, s)
+}
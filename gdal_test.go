@@ -0,0 +1,95 @@
+package tiff_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestImage_GDALMetadata(t *testing.T) {
+	const gdalXML = `<GDALMetadata>
+  <Item name="AREA_OR_POINT">Area</Item>
+  <Item name="SENSOR" domain="IMAGERY">MSI</Item>
+  <Item name="STATISTICS_MEAN" sample="0">101.5</Item>
+  <Item name="DESCRIPTION" sample="0" role="description">Red</Item>
+  <Item name="SCALE" sample="1" role="scale">0.0001</Item>
+  <Item name="OFFSET" sample="1" role="offset">-0.1</Item>
+</GDALMetadata>
+`
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 2, []int{16, 16}, 0)
+	im.SetTag(tiff.TagGDALMetadata, tiff.TagTypeASCII, gdalXML)
+	if err := im.SetGDALNoData(-9999); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.EncodeImage(make([]uint16, 2*2*2)); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ims[0].GDALMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Domain("", tiff.GDALDataset), map[string]string{"AREA_OR_POINT": "Area"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("default domain = %v, want %v", got, want)
+	}
+	if v, ok := m.Get("IMAGERY", tiff.GDALDataset, "SENSOR"); !ok || v != "MSI" {
+		t.Errorf("SENSOR = %q, %v", v, ok)
+	}
+	if v, ok := m.Float(0, "STATISTICS_MEAN"); !ok || v != 101.5 {
+		t.Errorf("STATISTICS_MEAN = %v, %v", v, ok)
+	}
+	if v, ok := m.Description(0); !ok || v != "Red" {
+		t.Errorf("Description = %q, %v", v, ok)
+	}
+	if scale, offset, ok := m.ScaleOffset(1); !ok || scale != 0.0001 || offset != -0.1 {
+		t.Errorf("ScaleOffset = %v, %v, %v", scale, offset, ok)
+	}
+	if scale, offset, ok := m.ScaleOffset(0); ok || scale != 1 || offset != 0 {
+		t.Errorf("ScaleOffset of band 0 = %v, %v, %v", scale, offset, ok)
+	}
+	if v, ok := ims[0].GDALNoData(); !ok || v != -9999 {
+		t.Errorf("GDALNoData = %v, %v", v, ok)
+	}
+
+	// Round trip through SetGDALMetadata
+	m.Set("", 1, "DESCRIPTION", "NIR")
+	m.Set("", 1, "UNITTYPE", "W/m2")
+	im = enc.NewImage()
+	if err := im.SetGDALMetadata(m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := im.GDALMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("round trip = %+v, want %+v", got, m)
+	}
+	for i, role := range []string{"description", "unittype"} {
+		if item := got.Items[len(got.Items)-2+i]; item.Role != role {
+			t.Errorf("role of %s = %q", item.Name, item.Role)
+		}
+	}
+
+	if err := im.SetGDALNoData(math.NaN()); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := im.GDALNoData(); !ok || !math.IsNaN(v) {
+		t.Errorf("GDALNoData = %v, %v", v, ok)
+	}
+}
@@ -0,0 +1,512 @@
+package tiff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// GeoKeyID is the identifying code of a GeoKey in the GeoKeyDirectory of a GeoTIFF image.
+type GeoKeyID uint16
+
+// GeoKeys
+const (
+	// GeoTIFF Configuration
+	GeoKeyGTModelType  GeoKeyID = 1024 // Short. Type of the model coordinate system (ModelType...).
+	GeoKeyGTRasterType GeoKeyID = 1025 // Short. Whether a pixel represents an area or a point (Raster...).
+	GeoKeyGTCitation   GeoKeyID = 1026 // ASCII. Description of the coordinate system.
+
+	// Geographic CS Parameters
+	GeoKeyGeographicType        GeoKeyID = 2048 // Short. EPSG code of the geographic coordinate system.
+	GeoKeyGeogCitation          GeoKeyID = 2049 // ASCII. Description of the geographic coordinate system.
+	GeoKeyGeogGeodeticDatum     GeoKeyID = 2050 // Short. EPSG code of the geodetic datum.
+	GeoKeyGeogPrimeMeridian     GeoKeyID = 2051 // Short. EPSG code of the prime meridian.
+	GeoKeyGeogLinearUnits       GeoKeyID = 2052 // Short. EPSG code of the linear units of geographic parameters.
+	GeoKeyGeogLinearUnitSize    GeoKeyID = 2053 // Double. Size of user-defined linear units, in meters.
+	GeoKeyGeogAngularUnits      GeoKeyID = 2054 // Short. EPSG code of the angular units.
+	GeoKeyGeogAngularUnitSize   GeoKeyID = 2055 // Double. Size of user-defined angular units, in radians.
+	GeoKeyGeogEllipsoid         GeoKeyID = 2056 // Short. EPSG code of the ellipsoid.
+	GeoKeyGeogSemiMajorAxis     GeoKeyID = 2057 // Double. Semi-major axis of a user-defined ellipsoid.
+	GeoKeyGeogSemiMinorAxis     GeoKeyID = 2058 // Double. Semi-minor axis of a user-defined ellipsoid.
+	GeoKeyGeogInvFlattening     GeoKeyID = 2059 // Double. Inverse flattening of a user-defined ellipsoid.
+	GeoKeyGeogAzimuthUnits      GeoKeyID = 2060 // Short. EPSG code of the angular units of azimuths.
+	GeoKeyGeogPrimeMeridianLong GeoKeyID = 2061 // Double. Longitude of a user-defined prime meridian.
+
+	// Projected CS Parameters
+	GeoKeyProjectedCSType          GeoKeyID = 3072 // Short. EPSG code of the projected coordinate system.
+	GeoKeyPCSCitation              GeoKeyID = 3073 // ASCII. Description of the projected coordinate system.
+	GeoKeyProjection               GeoKeyID = 3074 // Short. EPSG code of the projection.
+	GeoKeyProjCoordTrans           GeoKeyID = 3075 // Short. Method of a user-defined projection.
+	GeoKeyProjLinearUnits          GeoKeyID = 3076 // Short. EPSG code of the linear units of the projection.
+	GeoKeyProjLinearUnitSize       GeoKeyID = 3077 // Double. Size of user-defined linear units, in meters.
+	GeoKeyProjStdParallel1         GeoKeyID = 3078 // Double.
+	GeoKeyProjStdParallel2         GeoKeyID = 3079 // Double.
+	GeoKeyProjNatOriginLong        GeoKeyID = 3080 // Double.
+	GeoKeyProjNatOriginLat         GeoKeyID = 3081 // Double.
+	GeoKeyProjFalseEasting         GeoKeyID = 3082 // Double.
+	GeoKeyProjFalseNorthing        GeoKeyID = 3083 // Double.
+	GeoKeyProjFalseOriginLong      GeoKeyID = 3084 // Double.
+	GeoKeyProjFalseOriginLat       GeoKeyID = 3085 // Double.
+	GeoKeyProjFalseOriginEasting   GeoKeyID = 3086 // Double.
+	GeoKeyProjFalseOriginNorthing  GeoKeyID = 3087 // Double.
+	GeoKeyProjCenterLong           GeoKeyID = 3088 // Double.
+	GeoKeyProjCenterLat            GeoKeyID = 3089 // Double.
+	GeoKeyProjCenterEasting        GeoKeyID = 3090 // Double.
+	GeoKeyProjCenterNorthing       GeoKeyID = 3091 // Double.
+	GeoKeyProjScaleAtNatOrigin     GeoKeyID = 3092 // Double.
+	GeoKeyProjScaleAtCenter        GeoKeyID = 3093 // Double.
+	GeoKeyProjAzimuthAngle         GeoKeyID = 3094 // Double.
+	GeoKeyProjStraightVertPoleLong GeoKeyID = 3095 // Double.
+
+	// Vertical CS Parameters
+	GeoKeyVerticalCSType   GeoKeyID = 4096 // Short. EPSG code of the vertical coordinate system.
+	GeoKeyVerticalCitation GeoKeyID = 4097 // ASCII. Description of the vertical coordinate system.
+	GeoKeyVerticalDatum    GeoKeyID = 4098 // Short. EPSG code of the vertical datum.
+	GeoKeyVerticalUnits    GeoKeyID = 4099 // Short. EPSG code of the vertical units.
+)
+
+var geoKeyName = map[GeoKeyID]string{
+	// GeoTIFF Configuration
+	GeoKeyGTModelType:  "GTModelType",
+	GeoKeyGTRasterType: "GTRasterType",
+	GeoKeyGTCitation:   "GTCitation",
+
+	// Geographic CS Parameters
+	GeoKeyGeographicType:        "GeographicType",
+	GeoKeyGeogCitation:          "GeogCitation",
+	GeoKeyGeogGeodeticDatum:     "GeogGeodeticDatum",
+	GeoKeyGeogPrimeMeridian:     "GeogPrimeMeridian",
+	GeoKeyGeogLinearUnits:       "GeogLinearUnits",
+	GeoKeyGeogLinearUnitSize:    "GeogLinearUnitSize",
+	GeoKeyGeogAngularUnits:      "GeogAngularUnits",
+	GeoKeyGeogAngularUnitSize:   "GeogAngularUnitSize",
+	GeoKeyGeogEllipsoid:         "GeogEllipsoid",
+	GeoKeyGeogSemiMajorAxis:     "GeogSemiMajorAxis",
+	GeoKeyGeogSemiMinorAxis:     "GeogSemiMinorAxis",
+	GeoKeyGeogInvFlattening:     "GeogInvFlattening",
+	GeoKeyGeogAzimuthUnits:      "GeogAzimuthUnits",
+	GeoKeyGeogPrimeMeridianLong: "GeogPrimeMeridianLong",
+
+	// Projected CS Parameters
+	GeoKeyProjectedCSType:          "ProjectedCSType",
+	GeoKeyPCSCitation:              "PCSCitation",
+	GeoKeyProjection:               "Projection",
+	GeoKeyProjCoordTrans:           "ProjCoordTrans",
+	GeoKeyProjLinearUnits:          "ProjLinearUnits",
+	GeoKeyProjLinearUnitSize:       "ProjLinearUnitSize",
+	GeoKeyProjStdParallel1:         "ProjStdParallel1",
+	GeoKeyProjStdParallel2:         "ProjStdParallel2",
+	GeoKeyProjNatOriginLong:        "ProjNatOriginLong",
+	GeoKeyProjNatOriginLat:         "ProjNatOriginLat",
+	GeoKeyProjFalseEasting:         "ProjFalseEasting",
+	GeoKeyProjFalseNorthing:        "ProjFalseNorthing",
+	GeoKeyProjFalseOriginLong:      "ProjFalseOriginLong",
+	GeoKeyProjFalseOriginLat:       "ProjFalseOriginLat",
+	GeoKeyProjFalseOriginEasting:   "ProjFalseOriginEasting",
+	GeoKeyProjFalseOriginNorthing:  "ProjFalseOriginNorthing",
+	GeoKeyProjCenterLong:           "ProjCenterLong",
+	GeoKeyProjCenterLat:            "ProjCenterLat",
+	GeoKeyProjCenterEasting:        "ProjCenterEasting",
+	GeoKeyProjCenterNorthing:       "ProjCenterNorthing",
+	GeoKeyProjScaleAtNatOrigin:     "ProjScaleAtNatOrigin",
+	GeoKeyProjScaleAtCenter:        "ProjScaleAtCenter",
+	GeoKeyProjAzimuthAngle:         "ProjAzimuthAngle",
+	GeoKeyProjStraightVertPoleLong: "ProjStraightVertPoleLong",
+
+	// Vertical CS Parameters
+	GeoKeyVerticalCSType:   "VerticalCSType",
+	GeoKeyVerticalCitation: "VerticalCitation",
+	GeoKeyVerticalDatum:    "VerticalDatum",
+	GeoKeyVerticalUnits:    "VerticalUnits",
+}
+
+func (id GeoKeyID) String() string {
+	if name, ok := geoKeyName[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("GeoKey(%d)", uint16(id))
+}
+
+// GeoKey values
+const (
+	ModelTypeProjected  = 1 // Projected coordinate system
+	ModelTypeGeographic = 2 // Geographic latitude-longitude coordinate system
+	ModelTypeGeocentric = 3 // Geocentric (X, Y, Z) coordinate system
+
+	RasterPixelIsArea  = 1 // A pixel covers an area, whose top-left corner is at integer raster coordinates
+	RasterPixelIsPoint = 2 // A pixel is a point at integer raster coordinates
+
+	LinearMeter        = 9001 // Meter
+	LinearFoot         = 9002 // International foot
+	LinearFootUSSurvey = 9003 // US survey foot
+	AngularRadian      = 9101 // Radian
+	AngularDegree      = 9102 // Degree
+	AngularArcMinute   = 9103 // Arc-minute
+	AngularArcSecond   = 9104 // Arc-second
+	AngularGrad        = 9105 // Grad
+
+	GeoKeyUserDefined = 32767 // User-defined value of keys holding EPSG codes
+)
+
+// geoKeyEntrySize is the number of Shorts of the header and of each entry of the GeoKeyDirectory.
+const geoKeyEntrySize = 4
+
+// GeoKey is a key of the GeoKeyDirectory of a GeoTIFF image.
+//
+// Depending on its location, the value of a key is either Shorts, Doubles from GeoDoubleParams,
+// or an ASCII string from GeoASCIIParams.
+type GeoKey struct {
+	ID       GeoKeyID
+	Location TagID // Tag holding the value, or 0 when the value is in the entry of the key
+	Short    []uint16
+	Double   []float64
+	ASCII    string
+}
+
+// Uint returns the value of a Short key with a single value.
+func (k *GeoKey) Uint() (value int, ok bool) {
+	if k == nil || len(k.Short) != 1 {
+		return 0, false
+	}
+	return int(k.Short[0]), true
+}
+
+// Float returns the value of a Double key with a single value.
+func (k *GeoKey) Float() (value float64, ok bool) {
+	if k == nil || len(k.Double) != 1 {
+		return 0, false
+	}
+	return k.Double[0], true
+}
+
+// String returns the value of an ASCII key.
+func (k *GeoKey) String() (value string, ok bool) {
+	if k == nil || k.Location != TagGeoASCIIParams {
+		return "", false
+	}
+	return k.ASCII, true
+}
+
+// GeoKeys is the decoded GeoKeyDirectory of a GeoTIFF image.
+type GeoKeys struct {
+	Version       int // Version of the key directory, always 1
+	Revision      int // Major revision of the keys
+	MinorRevision int // Minor revision of the keys
+	Keys          []*GeoKey
+}
+
+// GeoKeys decodes the GeoKeyDirectory of the image with the values it references in
+// GeoDoubleParams and GeoASCIIParams.
+//
+// It returns (nil, nil) when the image has no GeoKeyDirectory.
+func (im *Image) GeoKeys() (*GeoKeys, error) {
+	tag := im.Tag[TagGeoKeyDirectory]
+	if tag == nil {
+		return nil, nil
+	}
+	if tag.Type != TagTypeShort || tag.Count < geoKeyEntrySize {
+		return nil, FormatError("invalid GeoKeyDirectory")
+	}
+	dir := DecodeShort(tag.Count, tag.Data, tag.Header.ByteOrder)
+	numKeys := int(dir[3])
+	if len(dir) < geoKeyEntrySize*(1+numKeys) {
+		return nil, FormatError("GeoKeyDirectory is shorter than its number of keys")
+	}
+	doubles, _ := im.Tag[TagGeoDoubleParams].Value().DoubleSlice()
+	ascii, _ := im.Tag[TagGeoASCIIParams].String()
+
+	keys := &GeoKeys{
+		Version:       int(dir[0]),
+		Revision:      int(dir[1]),
+		MinorRevision: int(dir[2]),
+		Keys:          make([]*GeoKey, numKeys),
+	}
+	for i := range keys.Keys {
+		entry := dir[geoKeyEntrySize*(1+i):]
+		key := &GeoKey{
+			ID:       GeoKeyID(entry[0]),
+			Location: TagID(entry[1]),
+		}
+		count, offset := int(entry[2]), int(entry[3])
+		switch key.Location {
+		case 0:
+			key.Short = []uint16{entry[3]}
+		case TagGeoKeyDirectory:
+			if offset+count > len(dir) {
+				return nil, FormatError(fmt.Sprintf("value of GeoKey %v out of GeoKeyDirectory", key.ID))
+			}
+			key.Short = append([]uint16(nil), dir[offset:offset+count]...)
+		case TagGeoDoubleParams:
+			if offset+count > len(doubles) {
+				return nil, FormatError(fmt.Sprintf("value of GeoKey %v out of GeoDoubleParams", key.ID))
+			}
+			key.Double = append([]float64(nil), doubles[offset:offset+count]...)
+		case TagGeoASCIIParams:
+			if offset+count > len(ascii) {
+				return nil, FormatError(fmt.Sprintf("value of GeoKey %v out of GeoASCIIParams", key.ID))
+			}
+			// Strings are terminated by '|' in GeoASCIIParams
+			key.ASCII = strings.TrimSuffix(ascii[offset:offset+count], "|")
+		default:
+			return nil, UnsupportedError(fmt.Sprintf("location %d of GeoKey %v", key.Location, key.ID))
+		}
+		keys.Keys[i] = key
+	}
+	return keys, nil
+}
+
+// Key returns the key with given ID, or nil if there is none.
+func (keys *GeoKeys) Key(id GeoKeyID) *GeoKey {
+	if keys == nil {
+		return nil
+	}
+	for _, key := range keys.Keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// ModelType returns GTModelType, one of ModelType...
+func (keys *GeoKeys) ModelType() (int, bool) {
+	return keys.Key(GeoKeyGTModelType).Uint()
+}
+
+// RasterType returns GTRasterType, either RasterPixelIsArea or RasterPixelIsPoint.
+func (keys *GeoKeys) RasterType() (int, bool) {
+	return keys.Key(GeoKeyGTRasterType).Uint()
+}
+
+// EPSG returns the EPSG code of the coordinate system, from ProjectedCSType for projected models,
+// or GeographicType otherwise. It returns (0, false) for user-defined coordinate systems.
+func (keys *GeoKeys) EPSG() (int, bool) {
+	id := GeoKeyGeographicType
+	if modelType, _ := keys.ModelType(); modelType == ModelTypeProjected {
+		id = GeoKeyProjectedCSType
+	}
+	code, ok := keys.Key(id).Uint()
+	if !ok || code == GeoKeyUserDefined {
+		return 0, false
+	}
+	return code, true
+}
+
+// Citation returns GTCitation, or the citation of the projected or geographic coordinate system
+// if there is none.
+func (keys *GeoKeys) Citation() (string, bool) {
+	for _, id := range []GeoKeyID{GeoKeyGTCitation, GeoKeyPCSCitation, GeoKeyGeogCitation} {
+		if citation, ok := keys.Key(id).String(); ok {
+			return citation, true
+		}
+	}
+	return "", false
+}
+
+// LinearUnits returns the EPSG code of the linear units, from ProjLinearUnits or GeogLinearUnits.
+func (keys *GeoKeys) LinearUnits() (int, bool) {
+	if units, ok := keys.Key(GeoKeyProjLinearUnits).Uint(); ok {
+		return units, true
+	}
+	return keys.Key(GeoKeyGeogLinearUnits).Uint()
+}
+
+// AngularUnits returns the EPSG code of the angular units, from GeogAngularUnits.
+func (keys *GeoKeys) AngularUnits() (int, bool) {
+	return keys.Key(GeoKeyGeogAngularUnits).Uint()
+}
+
+// GeoTransform is an affine transform from raster space to model space, with coefficients in the order used by GDAL:
+//
+//	x = t[0] + column*t[1] + row*t[2]
+//	y = t[3] + column*t[4] + row*t[5]
+type GeoTransform [6]float64
+
+// Apply returns the model coordinates of raster coordinates (column, row).
+func (t GeoTransform) Apply(column, row float64) (x, y float64) {
+	return t[0] + column*t[1] + row*t[2], t[3] + column*t[4] + row*t[5]
+}
+
+// GeoTransform returns the transform from raster space to model space, from ModelTransformation,
+// or from the first ModelTiepoint and ModelPixelScale.
+//
+// Raster coordinates are as defined by GeoTIFF: with RasterPixelIsArea, (0, 0) is the top-left corner
+// of the top-left pixel; with RasterPixelIsPoint, it is the center of that pixel.
+func (im *Image) GeoTransform() (t GeoTransform, ok bool) {
+	if m, ok := im.Tag[TagModelTransformation].Value().DoubleSlice(); ok && len(m) == 16 {
+		return GeoTransform{m[3], m[0], m[1], m[7], m[4], m[5]}, true
+	}
+	tiepoint, ok := im.Tag[TagModelTiepoint].Value().DoubleSlice()
+	if !ok || len(tiepoint) < 6 {
+		return t, false
+	}
+	scale, ok := im.Tag[TagModelPixelScale].Value().DoubleSlice()
+	if !ok || len(scale) < 2 {
+		return t, false
+	}
+	i, j, x, y := tiepoint[0], tiepoint[1], tiepoint[3], tiepoint[4]
+	return GeoTransform{x - i*scale[0], scale[0], 0, y + j*scale[1], 0, -scale[1]}, true
+}
+
+// SetShort sets a key with Short values, which replaces any key with the same ID.
+func (keys *GeoKeys) SetShort(id GeoKeyID, value ...uint16) {
+	keys.set(&GeoKey{ID: id, Short: value})
+}
+
+// SetDouble sets a key with Double values, stored in GeoDoubleParams.
+func (keys *GeoKeys) SetDouble(id GeoKeyID, value ...float64) {
+	keys.set(&GeoKey{ID: id, Location: TagGeoDoubleParams, Double: value})
+}
+
+// SetASCII sets a key with an ASCII value, stored in GeoASCIIParams. The value must not contain '|'.
+func (keys *GeoKeys) SetASCII(id GeoKeyID, value string) {
+	keys.set(&GeoKey{ID: id, Location: TagGeoASCIIParams, ASCII: value})
+}
+
+// Delete removes the key with given ID.
+func (keys *GeoKeys) Delete(id GeoKeyID) {
+	for i, key := range keys.Keys {
+		if key.ID == id {
+			keys.Keys = append(keys.Keys[:i], keys.Keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// set replaces or inserts a key, keeping keys sorted by ID as required by GeoTIFF.
+func (keys *GeoKeys) set(key *GeoKey) {
+	i := sort.Search(len(keys.Keys), func(i int) bool { return keys.Keys[i].ID >= key.ID })
+	if i < len(keys.Keys) && keys.Keys[i].ID == key.ID {
+		keys.Keys[i] = key
+		return
+	}
+	keys.Keys = append(keys.Keys, nil)
+	copy(keys.Keys[i+1:], keys.Keys[i:])
+	keys.Keys[i] = key
+}
+
+// SetGeoKeys sets the GeoKeyDirectory of the image, and the GeoDoubleParams and GeoASCIIParams
+// holding the values of its keys. A zero Version is written as version 1.1.0.
+func (im *Image) SetGeoKeys(keys *GeoKeys) error {
+	version := []uint16{uint16(keys.Version), uint16(keys.Revision), uint16(keys.MinorRevision)}
+	if keys.Version == 0 {
+		version = []uint16{1, 1, 0}
+	}
+	sorted := append([]*GeoKey(nil), keys.Keys...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	//
+	// Entries are followed by the Shorts of keys with several values
+	//
+	dir := append(version, uint16(len(sorted)))
+	var extra []uint16
+	var doubles []float64
+	var ascii strings.Builder
+	for _, key := range sorted {
+		switch {
+		case key.Location == TagGeoASCIIParams:
+			if strings.Contains(key.ASCII, "|") {
+				return fmt.Errorf("value of GeoKey %v contains '|'", key.ID)
+			}
+			dir = append(dir, uint16(key.ID), uint16(TagGeoASCIIParams), uint16(len(key.ASCII)+1), uint16(ascii.Len()))
+			ascii.WriteString(key.ASCII)
+			ascii.WriteByte('|')
+		case key.Location == TagGeoDoubleParams && len(key.Double) == 0:
+			return fmt.Errorf("GeoKey %v in GeoDoubleParams has no Double value", key.ID)
+		case key.Double != nil:
+			dir = append(dir, uint16(key.ID), uint16(TagGeoDoubleParams), uint16(len(key.Double)), uint16(len(doubles)))
+			doubles = append(doubles, key.Double...)
+		case len(key.Short) == 1:
+			dir = append(dir, uint16(key.ID), 0, 1, key.Short[0])
+		default:
+			offset := geoKeyEntrySize*(1+len(sorted)) + len(extra)
+			dir = append(dir, uint16(key.ID), uint16(TagGeoKeyDirectory), uint16(len(key.Short)), uint16(offset))
+			extra = append(extra, key.Short...)
+		}
+	}
+	dir = append(dir, extra...)
+	if len(dir) > math.MaxUint16 || len(doubles) > math.MaxUint16 || ascii.Len() > math.MaxUint16 {
+		return fmt.Errorf("too many GeoKey values")
+	}
+
+	if err := im.SetTag(TagGeoKeyDirectory, TagTypeShort, dir); err != nil {
+		return err
+	}
+	delete(im.Tag, TagGeoDoubleParams)
+	delete(im.Tag, TagGeoASCIIParams)
+	if len(doubles) > 0 {
+		if err := im.SetTag(TagGeoDoubleParams, TagTypeDouble, doubles); err != nil {
+			return err
+		}
+	}
+	if ascii.Len() > 0 {
+		if err := im.SetTag(TagGeoASCIIParams, TagTypeASCII, ascii.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetEPSG sets the coordinate system of the image to the EPSG code of a projected coordinate system
+// with ModelTypeProjected, or of a geographic coordinate system with ModelTypeGeographic.
+// GeoKeys hold codes as Short, so code must be within 1..65535.
+//
+// Other keys of the image are kept, except the EPSG code of the other model type.
+// GTRasterType is set to RasterPixelIsArea if it is not already set.
+func (im *Image) SetEPSG(modelType int, code int) error {
+	if code < 1 || code > math.MaxUint16 {
+		return fmt.Errorf("EPSG code %d out of range", code)
+	}
+	keys, err := im.GeoKeys()
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		keys = &GeoKeys{}
+	}
+	switch modelType {
+	case ModelTypeProjected:
+		keys.Delete(GeoKeyGeographicType)
+		keys.SetShort(GeoKeyProjectedCSType, uint16(code))
+	case ModelTypeGeographic:
+		keys.Delete(GeoKeyProjectedCSType)
+		keys.SetShort(GeoKeyGeographicType, uint16(code))
+	default:
+		return fmt.Errorf("invalid model type %d for an EPSG code", modelType)
+	}
+	keys.SetShort(GeoKeyGTModelType, uint16(modelType))
+	if keys.Key(GeoKeyGTRasterType) == nil {
+		keys.SetShort(GeoKeyGTRasterType, RasterPixelIsArea)
+	}
+	return im.SetGeoKeys(keys)
+}
+
+// SetGeoTransform sets the transform from raster space to model space.
+//
+// Transforms without rotation are written as ModelTiepoint and ModelPixelScale, as done by GDAL,
+// and other transforms as ModelTransformation.
+func (im *Image) SetGeoTransform(t GeoTransform) error {
+	if t[2] == 0 && t[4] == 0 {
+		return im.SetGeoTiepoint(0, 0, t[0], t[3], t[1], -t[5])
+	}
+	delete(im.Tag, TagModelTiepoint)
+	delete(im.Tag, TagModelPixelScale)
+	return im.SetTag(TagModelTransformation, TagTypeDouble, []float64{
+		t[1], t[2], 0, t[0],
+		t[4], t[5], 0, t[3],
+		0, 0, 0, 0,
+		0, 0, 0, 1,
+	})
+}
+
+// SetGeoTiepoint sets ModelTiepoint, tying raster coordinates (column, row) to model coordinates (x, y),
+// and ModelPixelScale, the size of pixels in model space. scaleY is positive when y decreases with rows,
+// as for north-up images.
+func (im *Image) SetGeoTiepoint(column, row, x, y, scaleX, scaleY float64) error {
+	delete(im.Tag, TagModelTransformation)
+	if err := im.SetTag(TagModelTiepoint, TagTypeDouble, []float64{column, row, 0, x, y, 0}); err != nil {
+		return err
+	}
+	return im.SetTag(TagModelPixelScale, TagTypeDouble, []float64{scaleX, scaleY, 0})
+}
@@ -0,0 +1,166 @@
+package tiff_test
+
+import (
+	"bytes"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestImage_GeoKeys(t *testing.T) {
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(4, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	im.SetTag(tiff.TagModelTiepoint, tiff.TagTypeDouble, []float64{0, 0, 0, 500000, 4000000, 0})
+	im.SetTag(tiff.TagModelPixelScale, tiff.TagTypeDouble, []float64{30, 30, 0})
+	im.SetTag(tiff.TagGeoKeyDirectory, tiff.TagTypeShort, []uint16{
+		1, 1, 0, 5,
+		1024, 0, 1, 1,
+		1025, 0, 1, 1,
+		1026, 34737, 22, 0,
+		2057, 34736, 1, 0,
+		3072, 0, 1, 32633,
+	})
+	im.SetTag(tiff.TagGeoDoubleParams, tiff.TagTypeDouble, []float64{6378137})
+	im.SetTag(tiff.TagGeoASCIIParams, tiff.TagTypeASCII, "WGS 84 / UTM zone 33N|")
+	if err := im.EncodeImage(make([]uint8, 4*2)); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ims[0].GeoKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys.Keys) != 5 {
+		t.Fatalf("found %d keys, want 5", len(keys.Keys))
+	}
+	if v, ok := keys.ModelType(); !ok || v != tiff.ModelTypeProjected {
+		t.Errorf("ModelType = %d, %v", v, ok)
+	}
+	if v, ok := keys.RasterType(); !ok || v != tiff.RasterPixelIsArea {
+		t.Errorf("RasterType = %d, %v", v, ok)
+	}
+	if v, ok := keys.EPSG(); !ok || v != 32633 {
+		t.Errorf("EPSG = %d, %v", v, ok)
+	}
+	if v, ok := keys.Citation(); !ok || v != "WGS 84 / UTM zone 33N" {
+		t.Errorf("Citation = %q, %v", v, ok)
+	}
+	if v, ok := keys.Key(tiff.GeoKeyGeogSemiMajorAxis).Float(); !ok || v != 6378137 {
+		t.Errorf("GeogSemiMajorAxis = %v, %v", v, ok)
+	}
+	if _, ok := keys.LinearUnits(); ok {
+		t.Errorf("LinearUnits found")
+	}
+
+	transform, ok := ims[0].GeoTransform()
+	if !ok {
+		t.Fatal("GeoTransform not found")
+	}
+	if want := (tiff.GeoTransform{500000, 30, 0, 4000000, 0, -30}); transform != want {
+		t.Errorf("GeoTransform = %v, want %v", transform, want)
+	}
+	if x, y := transform.Apply(4, 2); x != 500120 || y != 3999940 {
+		t.Errorf("Apply(4, 2) = (%v, %v)", x, y)
+	}
+}
+
+func TestImage_SetGeoKeys(t *testing.T) {
+	tests := []struct {
+		version   tiff.Version
+		transform tiff.GeoTransform
+	}{
+		{tiff.VersionClassicTIFF, tiff.GeoTransform{-180, 0.5, 0, 90, 0, -0.5}},
+		{tiff.VersionBigTIFF, tiff.GeoTransform{-180, 0.5, 0, 90, 0, -0.5}},
+		{tiff.VersionClassicTIFF, tiff.GeoTransform{1000, 0.8, 0.6, 2000, 0.6, -0.8}},
+		{tiff.VersionBigTIFF, tiff.GeoTransform{1000, 0.8, 0.6, 2000, 0.6, -0.8}},
+	}
+	for _, test := range tests {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		enc.SetVersion(test.version)
+		im := enc.NewImage()
+		im.SetWidthHeight(4, 2)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		if err := im.SetGeoTransform(test.transform); err != nil {
+			t.Fatal(err)
+		}
+		keys := &tiff.GeoKeys{}
+		keys.SetASCII(tiff.GeoKeyGeogCitation, "WGS 84")
+		keys.SetShort(tiff.GeoKeyGeogAngularUnits, tiff.AngularDegree)
+		keys.SetDouble(tiff.GeoKeyGeogSemiMajorAxis, 6378137)
+		keys.SetDouble(tiff.GeoKeyGeogInvFlattening, 298.257223563)
+		keys.SetShort(tiff.GeoKeyGeogPrimeMeridian, 8901, 8901)
+		keys.SetASCII(tiff.GeoKeyGTCitation, "test")
+		if err := im.SetGeoKeys(keys); err != nil {
+			t.Fatal(err)
+		}
+		if err := im.SetEPSG(tiff.ModelTypeGeographic, 4326); err != nil {
+			t.Fatal(err)
+		}
+		for _, code := range []int{0, -1, 70000} {
+			if err := im.SetEPSG(tiff.ModelTypeProjected, code); err == nil {
+				t.Errorf("no error for EPSG code %d", code)
+			}
+		}
+		invalid := &tiff.GeoKeys{Keys: []*tiff.GeoKey{{ID: tiff.GeoKeyGeogSemiMajorAxis, Location: tiff.TagGeoDoubleParams}}}
+		if err := im.SetGeoKeys(invalid); err == nil {
+			t.Errorf("no error for GeoDoubleParams key without Double")
+		}
+		if err := im.EncodeImage(make([]uint8, 4*2)); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ims, err := d.Iter().All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transform, ok := ims[0].GeoTransform(); !ok || transform != test.transform {
+			t.Errorf("%v: GeoTransform = %v, want %v", test.version, transform, test.transform)
+		}
+		got, err := ims[0].GeoKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != 1 || got.Revision != 1 || len(got.Keys) != 9 {
+			t.Fatalf("%v: version %d.%d with %d keys", test.version, got.Version, got.Revision, len(got.Keys))
+		}
+		for i := 1; i < len(got.Keys); i++ {
+			if got.Keys[i-1].ID >= got.Keys[i].ID {
+				t.Errorf("%v: keys are not sorted", test.version)
+			}
+		}
+		if v, ok := got.EPSG(); !ok || v != 4326 {
+			t.Errorf("%v: EPSG = %d, %v", test.version, v, ok)
+		}
+		if v, ok := got.RasterType(); !ok || v != tiff.RasterPixelIsArea {
+			t.Errorf("%v: RasterType = %d, %v", test.version, v, ok)
+		}
+		if v, ok := got.Citation(); !ok || v != "test" {
+			t.Errorf("%v: Citation = %q, %v", test.version, v, ok)
+		}
+		if v, ok := got.Key(tiff.GeoKeyGeogCitation).String(); !ok || v != "WGS 84" {
+			t.Errorf("%v: GeogCitation = %q, %v", test.version, v, ok)
+		}
+		if v, ok := got.Key(tiff.GeoKeyGeogInvFlattening).Float(); !ok || v != 298.257223563 {
+			t.Errorf("%v: GeogInvFlattening = %v, %v", test.version, v, ok)
+		}
+		if v := got.Key(tiff.GeoKeyGeogPrimeMeridian).Short; len(v) != 2 || v[0] != 8901 || v[1] != 8901 {
+			t.Errorf("%v: GeogPrimeMeridian = %v", test.version, v)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package tiff
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+func init() {
+	image.RegisterFormat("tiff", "II*\x00", Decode, DecodeConfig)
+	image.RegisterFormat("tiff", "MM\x00*", Decode, DecodeConfig)
+	image.RegisterFormat("tiff", "II+\x00", Decode, DecodeConfig)
+	image.RegisterFormat("tiff", "MM\x00+", Decode, DecodeConfig)
+}
+
+// Decode reads the first image of a Classic TIFF or BigTIFF file from r and returns it as an image.Image.
+//
+// If r does not implement io.ReaderAt, the whole file is read into memory.
+func Decode(r io.Reader) (image.Image, error) {
+	im, err := decodeFirstImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return im.GoImage()
+}
+
+// DecodeConfig returns the color model and dimensions of the first image of a Classic TIFF or BigTIFF file
+// without decoding the image data.
+//
+// If r does not implement io.ReaderAt, the whole file is read into memory.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	im, err := decodeFirstImage(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	model, err := im.goColorModel()
+	if err != nil {
+		return image.Config{}, err
+	}
+	width, height := im.WidthHeight()
+	return image.Config{
+		ColorModel: model,
+		Width:      width,
+		Height:     height,
+	}, nil
+}
+
+func decodeFirstImage(r io.Reader) (*Image, error) {
+	rd, ok := r.(io.ReaderAt)
+	if !ok {
+		buf, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(buf)
+	}
+
+	d, err := NewDecoder(rd)
+	if err != nil {
+		return nil, err
+	}
+	it := d.Iter()
+	if !it.Next() {
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+		return nil, FormatError("no image found")
+	}
+	return it.Image(), nil
+}
+
+func (im *Image) unsupportedPixelFormat() error {
+	return UnsupportedError(fmt.Sprintf("Photometric %d with BitsPerSample %v", im.Photometric(), im.BitsPerSample()))
+}
+
+// goColorModel returns the color model of the image, or an UnsupportedError if the image data
+// cannot be converted to an image.Image.
+func (im *Image) goColorModel() (color.Model, error) {
+	model := im.ColorModel()
+	if model == nil {
+		return nil, im.unsupportedPixelFormat()
+	}
+
+	// Only unsigned integer samples are supported. Default = 1. (TIFF 6.0 Page 80)
+	if sampleFormat, ok := im.Tag[TagSampleFormat].UintSlice(); ok {
+		for _, v := range sampleFormat {
+			if v != SampleFormatUint {
+				return nil, UnsupportedError(fmt.Sprintf("SampleFormat of %v", sampleFormat))
+			}
+		}
+	}
+
+	// Chrominance is decoded as one sample per pixel, which excludes subsampled YCbCr.
+	// JPEG data is upsampled by the JPEG decoder. Default = 2, 2. (TIFF 6.0 Page 91)
+	if im.Photometric() == PhotometricYCbCr && im.Compression() != CompressionJPEG {
+		subSampling, ok := im.Tag[TagYCbCrSubSampling].UintSlice()
+		if !ok || len(subSampling) != 2 || subSampling[0] != 1 || subSampling[1] != 1 {
+			if !ok {
+				subSampling = []uint{2, 2}
+			}
+			return nil, UnsupportedError(fmt.Sprintf("YCbCrSubSampling of %v", subSampling))
+		}
+	}
+	return model, nil
+}
+
+// palette returns the color map of a palette-color image, or nil if it is missing or invalid.
+func (im *Image) palette() color.Palette {
+	bitDepth := im.BitDepth()
+	if bitDepth == 0 || bitDepth > 8 {
+		return nil
+	}
+	colorMap, ok := im.Tag[TagColorMap].UintSlice()
+	n := 1 << uint(bitDepth)
+	if !ok || len(colorMap) != 3*n {
+		return nil
+	}
+	p := make(color.Palette, n)
+	for i := range p {
+		p[i] = color.RGBA64{
+			R: uint16(colorMap[i]),
+			G: uint16(colorMap[n+i]),
+			B: uint16(colorMap[2*n+i]),
+			A: 0xffff,
+		}
+	}
+	return p
+}
+
+// GoImage decodes the image data and returns it as an image.Image.
+//
+// The concrete type is determined by ColorModel, and is one of *image.Gray, *image.Gray16, *image.RGBA,
+// *image.RGBA64, *image.NRGBA, *image.NRGBA64, *image.YCbCr, *image.CMYK or *image.Paletted.
+// WhiteIsZero images are inverted, and gray images with an alpha channel are expanded to RGBA.
+// Signed or floating point samples and subsampled YCbCr images return an UnsupportedError.
+func (im *Image) GoImage() (image.Image, error) {
+	model, err := im.goColorModel()
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := im.WidthHeight()
+	rect := image.Rect(0, 0, width, height)
+	pixelCount := width * height
+	samplesPerPixel := im.SamplesPerPixel()
+	n := colorSamples(im.Photometric())
+	whiteIsZero := im.Photometric() == PhotometricWhiteIsZero
+	alpha := im.alpha()
+
+	if im.DataType() == Uint16 {
+		buf := make([]uint16, pixelCount*samplesPerPixel)
+		if err := im.DecodeImage(buf); err != nil {
+			return nil, err
+		}
+
+		if model == color.Gray16Model {
+			dst := image.NewGray16(rect)
+			for i := 0; i < pixelCount; i++ {
+				v := buf[i*samplesPerPixel]
+				if whiteIsZero {
+					v = 0xffff - v
+				}
+				dst.Pix[2*i] = uint8(v >> 8)
+				dst.Pix[2*i+1] = uint8(v)
+			}
+			return dst, nil
+		}
+
+		// RGBA64 or NRGBA64
+		pix := make([]uint8, pixelCount*8)
+		for i := 0; i < pixelCount; i++ {
+			s := buf[i*samplesPerPixel : (i+1)*samplesPerPixel]
+			a := uint16(0xffff)
+			if alpha != ExtraSamplesUnspecified {
+				a = s[n]
+			}
+			var r, g, b uint16
+			if n == 1 {
+				r = s[0]
+				if whiteIsZero {
+					if alpha == ExtraSamplesAssociatedAlpha {
+						r = a - minUint16(r, a)
+					} else {
+						r = 0xffff - r
+					}
+				}
+				g, b = r, r
+			} else {
+				r, g, b = s[0], s[1], s[2]
+			}
+			p := pix[8*i : 8*i+8]
+			p[0], p[1] = uint8(r>>8), uint8(r)
+			p[2], p[3] = uint8(g>>8), uint8(g)
+			p[4], p[5] = uint8(b>>8), uint8(b)
+			p[6], p[7] = uint8(a>>8), uint8(a)
+		}
+		if model == color.NRGBA64Model {
+			return &image.NRGBA64{Pix: pix, Stride: 8 * width, Rect: rect}, nil
+		}
+		return &image.RGBA64{Pix: pix, Stride: 8 * width, Rect: rect}, nil
+	}
+
+	buf := make([]uint8, pixelCount*samplesPerPixel)
+	if err := im.DecodeImage(buf); err != nil {
+		return nil, err
+	}
+
+	if p, ok := model.(color.Palette); ok {
+		dst := image.NewPaletted(rect, p)
+		for i := 0; i < pixelCount; i++ {
+			dst.Pix[i] = buf[i*samplesPerPixel]
+		}
+		return dst, nil
+	}
+
+	switch model {
+	case color.GrayModel:
+		dst := image.NewGray(rect)
+		for i := 0; i < pixelCount; i++ {
+			v := buf[i*samplesPerPixel]
+			if whiteIsZero {
+				v = 0xff - v
+			}
+			dst.Pix[i] = v
+		}
+		return dst, nil
+
+	case color.YCbCrModel:
+		dst := image.NewYCbCr(rect, image.YCbCrSubsampleRatio444)
+		for i := 0; i < pixelCount; i++ {
+			dst.Y[i] = buf[i*samplesPerPixel]
+			dst.Cb[i] = buf[i*samplesPerPixel+1]
+			dst.Cr[i] = buf[i*samplesPerPixel+2]
+		}
+		return dst, nil
+
+	case color.CMYKModel:
+		dst := image.NewCMYK(rect)
+		for i := 0; i < pixelCount; i++ {
+			copy(dst.Pix[4*i:4*i+4], buf[i*samplesPerPixel:i*samplesPerPixel+4])
+		}
+		return dst, nil
+	}
+
+	// RGBA or NRGBA
+	pix := make([]uint8, pixelCount*4)
+	for i := 0; i < pixelCount; i++ {
+		s := buf[i*samplesPerPixel : (i+1)*samplesPerPixel]
+		a := uint8(0xff)
+		if alpha != ExtraSamplesUnspecified {
+			a = s[n]
+		}
+		p := pix[4*i : 4*i+4]
+		if n == 1 {
+			v := s[0]
+			if whiteIsZero {
+				if alpha == ExtraSamplesAssociatedAlpha {
+					v = a - minUint8(v, a)
+				} else {
+					v = 0xff - v
+				}
+			}
+			p[0], p[1], p[2] = v, v, v
+		} else {
+			p[0], p[1], p[2] = s[0], s[1], s[2]
+		}
+		p[3] = a
+	}
+	if model == color.NRGBAModel {
+		return &image.NRGBA{Pix: pix, Stride: 4 * width, Rect: rect}, nil
+	}
+	return &image.RGBA{Pix: pix, Stride: 4 * width, Rect: rect}, nil
+}
+
+func minUint8(a, b uint8) uint8 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func minUint16(a, b uint16) uint16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// EncodeGoImage sets the width, height and pixel format of the image according to img,
+// and encodes the pixels of img.
+//
+// *image.Gray, *image.Gray16, *image.RGBA, *image.RGBA64, *image.NRGBA, *image.NRGBA64, *image.CMYK
+// and *image.Paletted are encoded in their own pixel format. Other images are converted to 8-bit RGB,
+// with an unassociated alpha channel unless the image reports itself as opaque.
+// A ColorMap has no alpha, so palettes with transparent colors return an UnsupportedError.
+func (im *Image) EncodeGoImage(img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	im.SetWidthHeight(width, height)
+
+	switch m := img.(type) {
+	case *image.Gray:
+		im.SetPixelFormat(PhotometricBlackIsZero, 1, []int{8})
+		return im.EncodeImage(packPix(m.Pix, m.Stride, width, height, 1))
+	case *image.Gray16:
+		im.SetPixelFormat(PhotometricBlackIsZero, 1, []int{16})
+		return im.EncodeImage(packPix16(m.Pix, m.Stride, width, height, 1))
+	case *image.RGBA:
+		im.SetPixelFormat(PhotometricRGB, 4, []int{8, 8, 8, 8}, ExtraSamplesAssociatedAlpha)
+		return im.EncodeImage(packPix(m.Pix, m.Stride, width, height, 4))
+	case *image.NRGBA:
+		im.SetPixelFormat(PhotometricRGB, 4, []int{8, 8, 8, 8}, ExtraSamplesUnassociatedAlpha)
+		return im.EncodeImage(packPix(m.Pix, m.Stride, width, height, 4))
+	case *image.RGBA64:
+		im.SetPixelFormat(PhotometricRGB, 4, []int{16, 16, 16, 16}, ExtraSamplesAssociatedAlpha)
+		return im.EncodeImage(packPix16(m.Pix, m.Stride, width, height, 4))
+	case *image.NRGBA64:
+		im.SetPixelFormat(PhotometricRGB, 4, []int{16, 16, 16, 16}, ExtraSamplesUnassociatedAlpha)
+		return im.EncodeImage(packPix16(m.Pix, m.Stride, width, height, 4))
+	case *image.CMYK:
+		im.SetPixelFormat(PhotometricSeparated, 4, []int{8, 8, 8, 8})
+		return im.EncodeImage(packPix(m.Pix, m.Stride, width, height, 4))
+	case *image.Paletted:
+		if len(m.Palette) > 256 {
+			return UnsupportedError(fmt.Sprintf("palette of %d colors", len(m.Palette)))
+		}
+		colorMap := make([]uint16, 3*256)
+		for i, c := range m.Palette {
+			r, g, b, a := c.RGBA()
+			if a != 0xffff {
+				return UnsupportedError(fmt.Sprintf("palette with transparent color %d", i))
+			}
+			colorMap[i] = uint16(r)
+			colorMap[256+i] = uint16(g)
+			colorMap[512+i] = uint16(b)
+		}
+		im.SetPixelFormat(PhotometricPalette, 1, []int{8})
+		im.SetTag(TagColorMap, TagTypeShort, colorMap)
+		return im.EncodeImage(packPix(m.Pix, m.Stride, width, height, 1))
+	}
+
+	opaque := false
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		opaque = o.Opaque()
+	}
+	samplesPerPixel := 4
+	if opaque {
+		samplesPerPixel = 3
+	}
+	buf := make([]uint8, width*height*samplesPerPixel)
+	i := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			buf[i], buf[i+1], buf[i+2] = c.R, c.G, c.B
+			if !opaque {
+				buf[i+3] = c.A
+			}
+			i += samplesPerPixel
+		}
+	}
+	if opaque {
+		im.SetPixelFormat(PhotometricRGB, 3, []int{8, 8, 8})
+	} else {
+		im.SetPixelFormat(PhotometricRGB, 4, []int{8, 8, 8, 8}, ExtraSamplesUnassociatedAlpha)
+	}
+	return im.EncodeImage(buf)
+}
+
+// packPix returns the 8-bit samples of an image from the image package without row padding.
+func packPix(pix []uint8, stride int, width int, height int, samplesPerPixel int) []uint8 {
+	bytesPerRow := width * samplesPerPixel
+	if stride == bytesPerRow {
+		return pix[:bytesPerRow*height]
+	}
+	buf := make([]uint8, bytesPerRow*height)
+	for y := 0; y < height; y++ {
+		copy(buf[y*bytesPerRow:(y+1)*bytesPerRow], pix[y*stride:y*stride+bytesPerRow])
+	}
+	return buf
+}
+
+// packPix16 returns the big-endian 16-bit samples of an image from the image package as []uint16.
+func packPix16(pix []uint8, stride int, width int, height int, samplesPerPixel int) []uint16 {
+	samplesPerRow := width * samplesPerPixel
+	buf := make([]uint16, samplesPerRow*height)
+	for y := 0; y < height; y++ {
+		row := pix[y*stride : y*stride+2*samplesPerRow]
+		for i := 0; i < samplesPerRow; i++ {
+			buf[y*samplesPerRow+i] = uint16(row[2*i])<<8 | uint16(row[2*i+1])
+		}
+	}
+	return buf
+}
@@ -0,0 +1,134 @@
+package tiff_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestGoImage_RoundTrip(t *testing.T) {
+	rect := image.Rect(0, 0, 5, 3)
+	gray := image.NewGray(rect)
+	gray16 := image.NewGray16(rect)
+	rgba := image.NewRGBA(rect)
+	nrgba64 := image.NewNRGBA64(rect)
+	cmyk := image.NewCMYK(rect)
+	paletted := image.NewPaletted(rect, color.Palette{
+		color.RGBA{0, 0, 0, 0xff},
+		color.RGBA{0xff, 0, 0, 0xff},
+		color.RGBA{0, 0xff, 0, 0xff},
+	})
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			v := uint8(16*y + x)
+			gray.SetGray(x, y, color.Gray{v})
+			gray16.SetGray16(x, y, color.Gray16{uint16(v) << 7})
+			rgba.SetRGBA(x, y, color.RGBA{v / 2, v / 3, v / 4, v})
+			nrgba64.SetNRGBA64(x, y, color.NRGBA64{uint16(v) * 3, uint16(v) * 5, uint16(v) * 7, 0xffff - uint16(v)})
+			cmyk.SetCMYK(x, y, color.CMYK{v, v + 1, v + 2, v + 3})
+			paletted.SetColorIndex(x, y, uint8(x%3))
+		}
+	}
+
+	for _, version := range []tiff.Version{tiff.VersionClassicTIFF, tiff.VersionBigTIFF} {
+		for _, src := range []image.Image{gray, gray16, rgba, nrgba64, cmyk, paletted} {
+			w := &writeSeeker{}
+			enc := tiff.NewEncoder(w)
+			enc.SetVersion(version)
+			if err := enc.NewImage().EncodeGoImage(src); err != nil {
+				t.Fatalf("%s %T: %v", version, src, err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("%s %T: %v", version, src, err)
+			}
+
+			config, format, err := image.DecodeConfig(bytes.NewReader(w.buf))
+			if err != nil {
+				t.Fatalf("%s %T: %v", version, src, err)
+			}
+			if format != "tiff" || config.Width != rect.Dx() || config.Height != rect.Dy() {
+				t.Errorf("%s %T: unexpected config %v %q", version, src, config, format)
+			}
+
+			dst, _, err := image.Decode(bytes.NewReader(w.buf))
+			if err != nil {
+				t.Fatalf("%s %T: %v", version, src, err)
+			}
+			if !sameImage(dst, src) {
+				t.Errorf("%s %T: decoded image differs from source", version, src)
+			}
+		}
+	}
+}
+
+// sameImage reports whether a and b are of the same type and have the same colors.
+func sameImage(a, b image.Image) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || a.Bounds() != b.Bounds() {
+		return false
+	}
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r0, g0, b0, a0 := a.At(x, y).RGBA()
+			r1, g1, b1, a1 := b.At(x, y).RGBA()
+			if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestGoImage_Unsupported(t *testing.T) {
+	encode := func(photometric int, samplesPerPixel int, bitsPerSample []int, tags func(im *tiff.Image)) *tiff.Image {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		im := enc.NewImage()
+		im.SetWidthHeight(2, 2)
+		im.SetPixelFormat(photometric, samplesPerPixel, bitsPerSample)
+		tags(im)
+		if err := im.EncodeImage(make([]uint8, 2*2*samplesPerPixel)); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return decodeFirst(t, w.buf)
+	}
+
+	tests := []struct {
+		name string
+		im   *tiff.Image
+	}{
+		{"signed", encode(tiff.PhotometricBlackIsZero, 1, []int{8}, func(im *tiff.Image) {
+			im.SetTag(tiff.TagSampleFormat, tiff.TagTypeShort, uint16(tiff.SampleFormatInt))
+		})},
+		{"default YCbCrSubSampling", encode(tiff.PhotometricYCbCr, 3, []int{8, 8, 8}, func(im *tiff.Image) {})},
+		{"YCbCr 4:2:0", encode(tiff.PhotometricYCbCr, 3, []int{8, 8, 8}, func(im *tiff.Image) {
+			im.SetTag(tiff.TagYCbCrSubSampling, tiff.TagTypeShort, []uint16{2, 2})
+		})},
+	}
+	for _, test := range tests {
+		var unsupported tiff.UnsupportedError
+		if _, err := test.im.GoImage(); !errors.As(err, &unsupported) {
+			t.Errorf("%s: GoImage error %v", test.name, err)
+		}
+	}
+
+	im := encode(tiff.PhotometricYCbCr, 3, []int{8, 8, 8}, func(im *tiff.Image) {
+		im.SetTag(tiff.TagYCbCrSubSampling, tiff.TagTypeShort, []uint16{1, 1})
+	})
+	if _, err := im.GoImage(); err != nil {
+		t.Errorf("YCbCr 4:4:4: %v", err)
+	}
+
+	paletted := image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.RGBA{}, color.RGBA{0xff, 0, 0, 0xff}})
+	if err := tiff.NewEncoder(&writeSeeker{}).NewImage().EncodeGoImage(paletted); err == nil {
+		t.Errorf("no error for transparent palette")
+	}
+}
@@ -0,0 +1,304 @@
+package tiff
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// GPS reference values
+const (
+	GPSSpeedKilometersPerHour = "K"
+	GPSSpeedMilesPerHour      = "M"
+	GPSSpeedKnots             = "N"
+
+	GPSDirectionTrue     = "T"
+	GPSDirectionMagnetic = "M"
+)
+
+// rationals decodes the values of a Rational GPS tag as float64.
+func (dir *GPSIFD) rationals(id GPSTagID) ([]float64, bool) {
+	if dir == nil {
+		return nil, false
+	}
+	r, ok := dir.Tag[id].Value().RationalSlice()
+	if !ok {
+		return nil, false
+	}
+	v := make([]float64, len(r))
+	for i := range r {
+		if r[i][1] == 0 {
+			return nil, false
+		}
+		v[i] = r[i].Float64()
+	}
+	return v, true
+}
+
+// string decodes the value of an ASCII GPS tag.
+func (dir *GPSIFD) string(id GPSTagID) (string, bool) {
+	if dir == nil {
+		return "", false
+	}
+	return dir.Tag[id].Value().String()
+}
+
+// degrees decodes a coordinate stored as degrees, minutes and seconds,
+// which is negated when its reference is negativeRef.
+func (dir *GPSIFD) degrees(id GPSTagID, refID GPSTagID, negativeRef string) (float64, bool) {
+	v, ok := dir.rationals(id)
+	if !ok {
+		return 0, false
+	}
+	degrees := v[0]
+	if len(v) > 1 {
+		degrees += v[1] / 60
+	}
+	if len(v) > 2 {
+		degrees += v[2] / 3600
+	}
+	if ref, _ := dir.string(refID); ref == negativeRef {
+		degrees = -degrees
+	}
+	return degrees, true
+}
+
+// LatLong returns the latitude and longitude in decimal degrees, negative in the south and the west.
+func (dir *GPSIFD) LatLong() (latitude float64, longitude float64, ok bool) {
+	latitude, ok = dir.degrees(GPSTagLatitude, GPSTagLatitudeRef, "S")
+	if !ok {
+		return 0, 0, false
+	}
+	longitude, ok = dir.degrees(GPSTagLongitude, GPSTagLongitudeRef, "W")
+	if !ok {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
+
+// Altitude returns the altitude in meters, negative below sea level.
+func (dir *GPSIFD) Altitude() (float64, bool) {
+	v, ok := dir.rationals(GPSTagAltitude)
+	if !ok {
+		return 0, false
+	}
+	altitude := v[0]
+	if ref, _ := dir.Tag[GPSTagAltitudeRef].Value().Byte(); ref == 1 {
+		altitude = -altitude
+	}
+	return altitude, true
+}
+
+// Time returns the UTC time of the position, from DateStamp and TimeStamp.
+func (dir *GPSIFD) Time() (time.Time, bool) {
+	date, ok := dir.string(GPSTagDateStamp)
+	if !ok {
+		return time.Time{}, false
+	}
+	day, err := time.Parse("2006:01:02", date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	v, ok := dir.rationals(GPSTagTimeStamp)
+	if !ok || len(v) != 3 {
+		return time.Time{}, false
+	}
+	seconds := v[0]*3600 + v[1]*60 + v[2]
+	return day.Add(time.Duration(math.Round(seconds * float64(time.Second)))), true
+}
+
+// Speed returns the speed of the GPS receiver in the unit of ref, one of GPSSpeed...
+func (dir *GPSIFD) Speed() (speed float64, ref string, ok bool) {
+	v, ok := dir.rationals(GPSTagSpeed)
+	if !ok {
+		return 0, "", false
+	}
+	ref, ok = dir.string(GPSTagSpeedRef)
+	if !ok {
+		ref = GPSSpeedKilometersPerHour
+	}
+	return v[0], ref, true
+}
+
+// Track returns the direction of movement in degrees, relative to the north of ref, one of GPSDirection...
+func (dir *GPSIFD) Track() (direction float64, ref string, ok bool) {
+	return dir.direction(GPSTagTrack, GPSTagTrackRef)
+}
+
+// ImgDirection returns the direction of the image in degrees, relative to the north of ref, one of GPSDirection...
+func (dir *GPSIFD) ImgDirection() (direction float64, ref string, ok bool) {
+	return dir.direction(GPSTagImgDirection, GPSTagImgDirectionRef)
+}
+
+func (dir *GPSIFD) direction(id GPSTagID, refID GPSTagID) (direction float64, ref string, ok bool) {
+	v, ok := dir.rationals(id)
+	if !ok {
+		return 0, "", false
+	}
+	ref, ok = dir.string(refID)
+	if !ok {
+		ref = GPSDirectionTrue
+	}
+	return v[0], ref, true
+}
+
+// MapDatum returns the geodetic datum of the position, e.g. "WGS-84".
+func (dir *GPSIFD) MapDatum() (string, bool) {
+	return dir.string(GPSTagMapDatum)
+}
+
+// SetGPSTag sets a tag of the GPS IFD, which is written after the IFD of the image.
+//
+// GPSVersionID is set to 2.3.0.0 when the GPS IFD is created.
+func (im *Image) SetGPSTag(id GPSTagID, tagType TagType, value interface{}) error {
+	if im.GPS == nil {
+		im.GPS = &GPSIFD{Header: im.Header}
+	}
+	if im.GPS.Tag == nil {
+		im.GPS.Tag = make(map[GPSTagID]*GPSTag)
+		if id != GPSTagVersionID {
+			if err := im.SetGPSTag(GPSTagVersionID, TagTypeByte, []byte{2, 3, 0, 0}); err != nil {
+				return err
+			}
+		}
+	}
+	tag, err := NewTag(TagID(id), tagType, value, im.Header)
+	if err != nil {
+		return err
+	}
+	im.GPS.Tag[id] = &GPSTag{
+		ID:     id,
+		Type:   tag.Type,
+		Count:  tag.Count,
+		Data:   tag.Data,
+		Header: im.Header,
+	}
+	return nil
+}
+
+// newRational returns the rational nearest to v with given denominator.
+func newRational(v float64, denominator uint32) (Rational, error) {
+	n := math.Round(v * float64(denominator))
+	if !(n >= 0 && n <= math.MaxUint32) {
+		return Rational{}, fmt.Errorf("%v out of range of Rational", v)
+	}
+	return NewRational(uint32(n), denominator), nil
+}
+
+// degreesRationals returns degrees, minutes and seconds of the absolute value of v, to the microsecond of arc.
+func degreesRationals(v float64) ([]Rational, error) {
+	const microsecondsPerDegree = 3600e6
+	if math.IsNaN(v) || math.Abs(v) > 360 {
+		return nil, fmt.Errorf("invalid coordinate %v", v)
+	}
+	total := uint64(math.Round(math.Abs(v) * microsecondsPerDegree))
+	return []Rational{
+		NewRational(uint32(total/microsecondsPerDegree), 1),
+		NewRational(uint32(total/60e6%60), 1),
+		NewRational(uint32(total%60e6), 1e6),
+	}, nil
+}
+
+// SetGPSLatLong sets the latitude and longitude in decimal degrees, negative in the south and the west.
+func (im *Image) SetGPSLatLong(latitude float64, longitude float64) error {
+	if !(latitude >= -90 && latitude <= 90) || !(longitude >= -180 && longitude <= 180) {
+		return fmt.Errorf("invalid latitude and longitude (%v, %v)", latitude, longitude)
+	}
+	latitudeRef, longitudeRef := "N", "E"
+	if latitude < 0 {
+		latitudeRef = "S"
+	}
+	if longitude < 0 {
+		longitudeRef = "W"
+	}
+	lat, err := degreesRationals(latitude)
+	if err != nil {
+		return err
+	}
+	long, err := degreesRationals(longitude)
+	if err != nil {
+		return err
+	}
+	if err := im.SetGPSTag(GPSTagLatitudeRef, TagTypeASCII, latitudeRef); err != nil {
+		return err
+	}
+	if err := im.SetGPSTag(GPSTagLatitude, TagTypeRational, lat); err != nil {
+		return err
+	}
+	if err := im.SetGPSTag(GPSTagLongitudeRef, TagTypeASCII, longitudeRef); err != nil {
+		return err
+	}
+	return im.SetGPSTag(GPSTagLongitude, TagTypeRational, long)
+}
+
+// SetGPSAltitude sets the altitude in meters, negative below sea level, to the millimeter.
+func (im *Image) SetGPSAltitude(altitude float64) error {
+	ref := byte(0)
+	if altitude < 0 {
+		ref = 1
+	}
+	r, err := newRational(math.Abs(altitude), 1000)
+	if err != nil {
+		return err
+	}
+	if err := im.SetGPSTag(GPSTagAltitudeRef, TagTypeByte, []byte{ref}); err != nil {
+		return err
+	}
+	return im.SetGPSTag(GPSTagAltitude, TagTypeRational, r)
+}
+
+// SetGPSTime sets DateStamp and TimeStamp to t in UTC, to the millisecond.
+func (im *Image) SetGPSTime(t time.Time) error {
+	t = t.UTC().Round(time.Millisecond)
+	seconds := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	timeStamp := []Rational{
+		NewRational(uint32(t.Hour()), 1),
+		NewRational(uint32(t.Minute()), 1),
+		NewRational(uint32(math.Round(seconds*1000)), 1000),
+	}
+	if err := im.SetGPSTag(GPSTagDateStamp, TagTypeASCII, t.Format("2006:01:02")); err != nil {
+		return err
+	}
+	return im.SetGPSTag(GPSTagTimeStamp, TagTypeRational, timeStamp)
+}
+
+// SetGPSSpeed sets the speed of the GPS receiver in the unit of ref, one of GPSSpeed..., to 1/1000.
+func (im *Image) SetGPSSpeed(speed float64, ref string) error {
+	r, err := newRational(speed, 1000)
+	if err != nil {
+		return err
+	}
+	if err := im.SetGPSTag(GPSTagSpeedRef, TagTypeASCII, ref); err != nil {
+		return err
+	}
+	return im.SetGPSTag(GPSTagSpeed, TagTypeRational, r)
+}
+
+// SetGPSTrack sets the direction of movement in degrees, relative to the north of ref, one of GPSDirection...
+func (im *Image) SetGPSTrack(direction float64, ref string) error {
+	return im.setGPSDirection(GPSTagTrack, GPSTagTrackRef, direction, ref)
+}
+
+// SetGPSImgDirection sets the direction of the image in degrees, relative to the north of ref, one of GPSDirection...
+func (im *Image) SetGPSImgDirection(direction float64, ref string) error {
+	return im.setGPSDirection(GPSTagImgDirection, GPSTagImgDirectionRef, direction, ref)
+}
+
+func (im *Image) setGPSDirection(id GPSTagID, refID GPSTagID, direction float64, ref string) error {
+	if !(direction >= 0 && direction < 360) {
+		return fmt.Errorf("invalid direction %v", direction)
+	}
+	r, err := newRational(direction, 100)
+	if err != nil {
+		return err
+	}
+	if err := im.SetGPSTag(refID, TagTypeASCII, ref); err != nil {
+		return err
+	}
+	return im.SetGPSTag(id, TagTypeRational, r)
+}
+
+// SetGPSMapDatum sets the geodetic datum of the position, e.g. "WGS-84".
+func (im *Image) SetGPSMapDatum(datum string) error {
+	return im.SetGPSTag(GPSTagMapDatum, TagTypeASCII, datum)
+}
@@ -0,0 +1,80 @@
+package tiff_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestImage_SetGPS(t *testing.T) {
+	when := time.Date(2021, 7, 14, 10, 30, 15, 250e6, time.FixedZone("CEST", 2*3600))
+	for _, version := range []tiff.Version{tiff.VersionClassicTIFF, tiff.VersionBigTIFF} {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		enc.SetVersion(version)
+		im := enc.NewImage()
+		im.SetWidthHeight(2, 2)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		for _, err := range []error{
+			im.SetGPSLatLong(-33.856784, 151.215297),
+			im.SetGPSAltitude(-12.5),
+			im.SetGPSTime(when),
+			im.SetGPSSpeed(42.195, tiff.GPSSpeedKnots),
+			im.SetGPSTrack(270.5, tiff.GPSDirectionMagnetic),
+			im.SetGPSImgDirection(12.25, tiff.GPSDirectionTrue),
+			im.SetGPSMapDatum("WGS-84"),
+		} {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := im.EncodeImage(make([]uint8, 2*2)); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ims, err := d.Iter().All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gps := ims[0].GPS
+		if gps == nil {
+			t.Fatalf("%v: GPS IFD not found", version)
+		}
+		if v := gps.Tag[tiff.GPSTagVersionID]; v == nil || !bytes.Equal(v.Data, []byte{2, 3, 0, 0}) {
+			t.Errorf("%v: GPSVersionID = %v", version, v)
+		}
+		if lat, long, ok := gps.LatLong(); !ok || math.Abs(lat+33.856784) > 1e-9 || math.Abs(long-151.215297) > 1e-9 {
+			t.Errorf("%v: LatLong = %v, %v, %v", version, lat, long, ok)
+		}
+		if v, ok := gps.Altitude(); !ok || v != -12.5 {
+			t.Errorf("%v: Altitude = %v, %v", version, v, ok)
+		}
+		if v, ok := gps.Time(); !ok || !v.Equal(when) {
+			t.Errorf("%v: Time = %v, %v, want %v", version, v, ok, when)
+		}
+		if v, ref, ok := gps.Speed(); !ok || v != 42.195 || ref != tiff.GPSSpeedKnots {
+			t.Errorf("%v: Speed = %v, %q, %v", version, v, ref, ok)
+		}
+		if v, ref, ok := gps.Track(); !ok || v != 270.5 || ref != tiff.GPSDirectionMagnetic {
+			t.Errorf("%v: Track = %v, %q, %v", version, v, ref, ok)
+		}
+		if v, ref, ok := gps.ImgDirection(); !ok || v != 12.25 || ref != tiff.GPSDirectionTrue {
+			t.Errorf("%v: ImgDirection = %v, %q, %v", version, v, ref, ok)
+		}
+		if v, ok := gps.MapDatum(); !ok || v != "WGS-84" {
+			t.Errorf("%v: MapDatum = %q, %v", version, v, ok)
+		}
+	}
+
+	var gps *tiff.GPSIFD
+	if _, _, ok := gps.LatLong(); ok {
+		t.Errorf("LatLong of nil GPSIFD is ok")
+	}
+}
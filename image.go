@@ -2,8 +2,11 @@ package tiff
 
 import (
 	"fmt"
+	"image/color"
 	"io"
+	"math"
 	"sort"
+	"sync"
 )
 
 // Image represents a Image File Directory (IFD).
@@ -16,11 +19,17 @@ type Image struct {
 	Interoperability *InteroperabilityIFD
 
 	// Filled by Encoder or Decoder
-	Header     *Header     // Header of the TIFF file
-	Offset     int64       // Offset of this IFD
-	OffsetNext int64       // Offset of the next IFD
-	rd         io.ReaderAt // Reader to access the whole TIFF file
-	enc        *Encoder    // To access WriterAt and current write offset
+	Header     *Header          // Header of the TIFF file
+	Offset     int64            // Offset of this IFD
+	OffsetNext int64            // Offset of the next IFD
+	rd         io.ReaderAt      // Reader to access the whole TIFF file
+	enc        *Encoder         // To access WriterAt and current write offset
+	segments   [][]byte         // Compressed segments kept until the Encoder is closed, with LayoutCOG
+	parent     *Image           // Image whose SubIFDs include this image, when encoding
+	overviews  *OverviewOptions // Overviews built by EncodeImage
+
+	layoutMu sync.Mutex     // Guards layout
+	layout   *segmentLayout // Segments with their offsets and byte counts, decoded once for all segments
 }
 
 // TagID returns a sorted slice of TagIDs of the Image.
@@ -42,6 +51,10 @@ func (im *Image) SetTag(id TagID, tagType TagType, value interface{}) error {
 		return err
 	}
 	im.Tag[id] = tag
+
+	im.layoutMu.Lock()
+	im.layout = nil
+	im.layoutMu.Unlock()
 	return nil
 }
 
@@ -50,10 +63,19 @@ func (im *Image) SetWidthHeight(width int, height int) {
 	im.SetTag(TagImageLength, TagTypeLong, uint32(height))
 }
 
-func (im *Image) SetPixelFormat(photometric int, samplePerPixel int, bitsPerSample []int) {
+// SetPixelFormat sets the color space, the number of samples per pixel and the bit depth of each sample.
+//
+// Samples beyond those required by the photometric interpretation can be declared with extraSamples,
+// using ExtraSamplesUnspecified, ExtraSamplesAssociatedAlpha or ExtraSamplesUnassociatedAlpha.
+func (im *Image) SetPixelFormat(photometric int, samplePerPixel int, bitsPerSample []int, extraSamples ...int) {
 	im.SetTag(TagPhotometric, TagTypeLong, uint32(photometric))
 	im.SetTag(TagSamplesPerPixel, TagTypeLong, uint32(samplePerPixel))
 	im.SetTag(TagBitsPerSample, TagTypeLong, bitsPerSample)
+	if len(extraSamples) > 0 {
+		im.SetTag(TagExtraSamples, TagTypeShort, extraSamples)
+	} else {
+		delete(im.Tag, TagExtraSamples)
+	}
 }
 
 func (im *Image) SetCompression(compression int) {
@@ -84,6 +106,34 @@ func (im *Image) WidthHeight() (width int, height int) {
 	return
 }
 
+// TileWidthHeight returns the width and height of tiles, or zeros if the image is not organized in tiles.
+func (im *Image) TileWidthHeight() (tileWidth int, tileHeight int) {
+	v, ok := im.Tag[TagTileWidth].Uint()
+	if !ok {
+		return
+	}
+	tileWidth = int(v)
+	v, ok = im.Tag[TagTileLength].Uint()
+	if !ok {
+		return
+	}
+	tileHeight = int(v)
+	return
+}
+
+// RowsPerStrip returns the number of rows per strip.
+//
+// If the RowsPerStrip tag is missing or exceeds the image height, it returns the image height.
+func (im *Image) RowsPerStrip() int {
+	_, height := im.WidthHeight()
+	v, ok := im.Tag[TagRowsPerStrip].Uint()
+	if !ok || int(v) > height {
+		// Default = 2**32-1, i.e. the whole image is one strip. (TIFF 6.0 Page 39)
+		return height
+	}
+	return int(v)
+}
+
 // SamplesPerPixel returns the number of samples (color channels) per pixel.
 func (im *Image) SamplesPerPixel() int {
 	v, ok := im.Tag[TagSamplesPerPixel].Uint()
@@ -93,6 +143,31 @@ func (im *Image) SamplesPerPixel() int {
 	return int(v)
 }
 
+// Photometric returns the color space of the image data, or -1 if the Photometric tag is missing.
+func (im *Image) Photometric() int {
+	v, ok := im.Tag[TagPhotometric].Uint()
+	if !ok {
+		return -1
+	}
+	return int(v)
+}
+
+// ExtraSamples returns the meaning of each extra sample of a pixel,
+// which is one of ExtraSamplesUnspecified, ExtraSamplesAssociatedAlpha or ExtraSamplesUnassociatedAlpha.
+//
+// Extra samples are stored after the color components required by the photometric interpretation.
+func (im *Image) ExtraSamples() []int {
+	v, ok := im.Tag[TagExtraSamples].UintSlice()
+	if !ok {
+		return []int{}
+	}
+	r := make([]int, len(v))
+	for i := 0; i < len(v); i++ {
+		r[i] = int(v[i])
+	}
+	return r
+}
+
 // PlanarConfig returns how the components of each pixel are stored.
 func (im *Image) PlanarConfig() int {
 	e := im.Tag[TagPlanarConfig]
@@ -154,6 +229,104 @@ func (im *Image) DataType() DataType {
 	}
 }
 
+// colorSamples returns the number of color components of a photometric interpretation,
+// or 0 if it is not known.
+func colorSamples(photometric int) int {
+	switch photometric {
+	case PhotometricWhiteIsZero, PhotometricBlackIsZero, PhotometricPalette, PhotometricMask:
+		return 1
+	case PhotometricRGB, PhotometricYCbCr, PhotometricCIELab, PhotometricICCLab, PhotometricITULab:
+		return 3
+	case PhotometricSeparated:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// alpha returns the kind of alpha channel following the color components,
+// which is ExtraSamplesUnspecified when the image does not have an alpha channel.
+func (im *Image) alpha() int {
+	n := colorSamples(im.Photometric())
+	if n == 0 || im.SamplesPerPixel() <= n {
+		return ExtraSamplesUnspecified
+	}
+	extraSamples := im.ExtraSamples()
+	if len(extraSamples) == 0 {
+		return ExtraSamplesUnspecified
+	}
+	return extraSamples[0]
+}
+
+// ColorModel returns the color model of the image in terms of the image/color package,
+// or nil if the pixel format does not have an equivalent.
+//
+// Gray and RGB images with an associated (pre-multiplied) alpha channel map to color.RGBAModel or color.RGBA64Model,
+// and those with an unassociated alpha channel map to color.NRGBAModel or color.NRGBA64Model.
+// Extra samples of unspecified meaning are not interpreted as alpha.
+func (im *Image) ColorModel() color.Model {
+	bitDepth := im.BitDepth()
+	if bitDepth != 8 && bitDepth != 16 {
+		return nil
+	}
+	photometric := im.Photometric()
+	n := colorSamples(photometric)
+	if n == 0 || im.SamplesPerPixel() < n {
+		return nil
+	}
+
+	switch photometric {
+	case PhotometricWhiteIsZero, PhotometricBlackIsZero, PhotometricRGB:
+		switch im.alpha() {
+		case ExtraSamplesAssociatedAlpha:
+			if bitDepth == 8 {
+				return color.RGBAModel
+			}
+			return color.RGBA64Model
+		case ExtraSamplesUnassociatedAlpha:
+			if bitDepth == 8 {
+				return color.NRGBAModel
+			}
+			return color.NRGBA64Model
+		}
+		if photometric == PhotometricRGB {
+			if bitDepth == 8 {
+				return color.RGBAModel
+			}
+			return color.RGBA64Model
+		}
+		if bitDepth == 8 {
+			return color.GrayModel
+		}
+		return color.Gray16Model
+	case PhotometricPalette:
+		if bitDepth == 8 {
+			if p := im.palette(); p != nil {
+				return p
+			}
+		}
+	case PhotometricYCbCr:
+		if bitDepth == 8 {
+			return color.YCbCrModel
+		}
+	case PhotometricSeparated:
+		if bitDepth == 8 {
+			return color.CMYKModel
+		}
+	}
+	return nil
+}
+
+// Predictor returns the prediction scheme applied to the image data before compression.
+func (im *Image) Predictor() int {
+	v, ok := im.Tag[TagPredictor].Uint()
+	if !ok {
+		// Default = 1. (TIFF 6.0 Page 64)
+		return PredictorNone
+	}
+	return int(v)
+}
+
 // Compression returns the compression scheme used on the image data
 func (im *Image) Compression() (compression int) {
 	v, ok := im.Tag[TagCompression].Uint()
@@ -166,6 +339,8 @@ func (im *Image) Compression() (compression int) {
 }
 
 // EncodeTags returns encoded tags with requested IFD offset.
+//
+// If the image has Exif or GPS tags, the Exif and GPS IFDs follow the IFD of the image in the returned bytes.
 func (im *Image) EncodeTags(offset int64) ([]byte, error) {
 	im.Offset = offset
 
@@ -176,11 +351,99 @@ func (im *Image) EncodeTags(offset int64) ([]byte, error) {
 	if im.Header == nil {
 		return nil, fmt.Errorf("missing Header")
 	}
+	private := im.privateIFDs()
+	if len(private) == 0 {
+		return im.Header.encodeIFD(im.tags(), offset, im.OffsetNext)
+	}
 
+	//
+	// Private IFDs are written after the IFD of the image, whose size does not depend on their offsets
+	//
+	for _, dir := range private {
+		if err := im.setIFDOffset(dir.id, 0); err != nil {
+			return nil, err
+		}
+	}
+	buf, err := im.Header.encodeIFD(im.tags(), offset, im.OffsetNext)
+	if err != nil {
+		return nil, err
+	}
+	end := offset + int64(len(buf))
+	for i := range private {
+		private[i].offset = wordAlign(end)
+		dir, err := im.Header.encodeIFD(private[i].tags, private[i].offset, 0)
+		if err != nil {
+			return nil, err
+		}
+		end = private[i].offset + int64(len(dir))
+		if err := im.setIFDOffset(private[i].id, private[i].offset); err != nil {
+			return nil, err
+		}
+	}
+	buf, err = im.Header.encodeIFD(im.tags(), offset, im.OffsetNext)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range private {
+		dir, err := im.Header.encodeIFD(p.tags, p.offset, 0)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, make([]byte, p.offset-offset-int64(len(buf)))...)
+		buf = append(buf, dir...)
+	}
+	if im.Exif != nil {
+		im.Exif.Header = im.Header
+	}
+	if im.GPS != nil {
+		im.GPS.Header = im.Header
+	}
+	return buf, nil
+}
+
+// privateIFD is an IFD referenced by a tag of the image, such as the Exif IFD.
+type privateIFD struct {
+	id     TagID // Tag holding the offset of the IFD
+	tags   []*Tag
+	offset int64
+}
+
+// privateIFDs returns the Exif and GPS IFDs of the image which have tags.
+func (im *Image) privateIFDs() []privateIFD {
+	var private []privateIFD
+	if im.Exif != nil && len(im.Exif.Tag) > 0 {
+		private = append(private, privateIFD{id: TagExifIFD, tags: im.Exif.tags()})
+	}
+	if im.GPS != nil && len(im.GPS.Tag) > 0 {
+		private = append(private, privateIFD{id: TagGPSIFD, tags: im.GPS.tags()})
+	}
+	return private
+}
+
+// tags returns the tags of the image sorted by ID.
+func (im *Image) tags() []*Tag {
+	ids := im.TagID()
+	tags := make([]*Tag, len(ids))
+	for i, id := range ids {
+		tags[i] = im.Tag[id]
+	}
+	return tags
+}
+
+// setIFDOffset sets a tag holding the offset of an IFD, as Long in Classic TIFF or IFD8 in BigTIFF.
+func (im *Image) setIFDOffset(id TagID, offset int64) error {
+	if im.Header.Version == VersionBigTIFF {
+		return im.SetTag(id, TagTypeIFD8, uint64(offset))
+	}
+	return im.SetTag(id, TagTypeLong, uint32(offset))
+}
+
+// encodeIFD returns an IFD with tags sorted by ID, at given offset.
+func (h *Header) encodeIFD(tags []*Tag, offset int64, offsetNext int64) ([]byte, error) {
 	var bytesOfNumTags int
 	var bytesOfEntry int
 	var byteOfOffset int
-	switch im.Header.Version {
+	switch h.Version {
 	case VersionClassicTIFF:
 		bytesOfNumTags = 2
 		bytesOfEntry = 12
@@ -190,34 +453,36 @@ func (im *Image) EncodeTags(offset int64) ([]byte, error) {
 		bytesOfEntry = 20
 		byteOfOffset = 8
 	default:
-		panic("invalid TIFF version in header")
+		return nil, fmt.Errorf("cannot encode IFD of TIFF version %v", h.Version)
 	}
 
 	var byteOfExtended int
-	for _, tag := range im.Tag {
+	for _, tag := range tags {
 		if len(tag.Data) > byteOfOffset {
 			byteOfExtended += len(tag.Data)
 		}
 	}
 
-	buf := make([]byte, bytesOfNumTags+len(ids)*bytesOfEntry+byteOfOffset+byteOfExtended)
+	buf := make([]byte, bytesOfNumTags+len(tags)*bytesOfEntry+byteOfOffset+byteOfExtended)
+	if h.Version == VersionClassicTIFF && offset+int64(len(buf)) > math.MaxUint32 {
+		return nil, errClassicTIFFOverflow(offset)
+	}
 	bufOffset := 0
-	bufOffsetOffsetNext := bytesOfNumTags + len(ids)*bytesOfEntry
+	bufOffsetOffsetNext := bytesOfNumTags + len(tags)*bytesOfEntry
 	bufOffsetExtended := bufOffsetOffsetNext + byteOfOffset
 
-	byteOrder := im.Header.ByteOrder
-	if im.Header.Version == VersionClassicTIFF {
-		byteOrder.PutUint16(buf[:bytesOfNumTags], uint16(len(ids)))
-		byteOrder.PutUint32(buf[bufOffsetOffsetNext:bufOffsetOffsetNext+byteOfOffset], uint32(im.OffsetNext))
+	byteOrder := h.ByteOrder
+	if h.Version == VersionClassicTIFF {
+		byteOrder.PutUint16(buf[:bytesOfNumTags], uint16(len(tags)))
+		byteOrder.PutUint32(buf[bufOffsetOffsetNext:bufOffsetOffsetNext+byteOfOffset], uint32(offsetNext))
 	} else {
-		byteOrder.PutUint64(buf[:bytesOfNumTags], uint64(len(ids)))
-		byteOrder.PutUint64(buf[bufOffsetOffsetNext:bufOffsetOffsetNext+byteOfOffset], uint64(im.OffsetNext))
+		byteOrder.PutUint64(buf[:bytesOfNumTags], uint64(len(tags)))
+		byteOrder.PutUint64(buf[bufOffsetOffsetNext:bufOffsetOffsetNext+byteOfOffset], uint64(offsetNext))
 	}
 	bufOffset += bytesOfNumTags
 
-	for _, id := range ids {
-		tag := im.Tag[id]
-		if im.Header.Version == VersionClassicTIFF {
+	for _, tag := range tags {
+		if h.Version == VersionClassicTIFF {
 			byteOrder.PutUint16(buf[bufOffset:bufOffset+2], uint16(tag.ID))
 			byteOrder.PutUint16(buf[bufOffset+2:bufOffset+4], uint16(tag.Type))
 			byteOrder.PutUint32(buf[bufOffset+4:bufOffset+8], uint32(tag.Count))
@@ -265,6 +530,17 @@ func (dir *ExifIFD) TagID() []ExifTagID {
 	return list
 }
 
+// tags returns the Exif tags as TIFF tags sorted by ID.
+func (dir *ExifIFD) tags() []*Tag {
+	ids := dir.TagID()
+	tags := make([]*Tag, len(ids))
+	for i, id := range ids {
+		t := dir.Tag[id]
+		tags[i] = &Tag{ID: TagID(t.ID), Type: t.Type, Count: t.Count, Data: t.Data}
+	}
+	return tags
+}
+
 type GPSIFD struct {
 	Tag map[GPSTagID]*GPSTag
 
@@ -283,6 +559,17 @@ func (dir *GPSIFD) TagID() []GPSTagID {
 	return list
 }
 
+// tags returns the GPS tags as TIFF tags sorted by ID.
+func (dir *GPSIFD) tags() []*Tag {
+	ids := dir.TagID()
+	tags := make([]*Tag, len(ids))
+	for i, id := range ids {
+		t := dir.Tag[id]
+		tags[i] = &Tag{ID: TagID(t.ID), Type: t.Type, Count: t.Count, Data: t.Data}
+	}
+	return tags
+}
+
 type InteroperabilityIFD struct {
 	Tag map[InteroperabilityTagID]*InteroperabilityTag
 
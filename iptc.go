@@ -0,0 +1,453 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// IPTCID identifies an IPTC-IIM dataset by its record and dataset numbers, as record<<8 | dataset.
+type IPTCID uint16
+
+// IPTC-IIM datasets of the envelope record (1) and the application record (2)
+const (
+	IPTCCodedCharacterSet IPTCID = 1<<8 | 90 // Character set of the application record, ESC % G for UTF-8.
+
+	IPTCRecordVersion                 IPTCID = 2<<8 | 0  // Binary. Version of the application record, 4.
+	IPTCObjectName                    IPTCID = 2<<8 | 5  // Title of the object.
+	IPTCUrgency                       IPTCID = 2<<8 | 10 // From "1" (most urgent) to "8".
+	IPTCCategory                      IPTCID = 2<<8 | 15 // Deprecated.
+	IPTCSupplementalCategories        IPTCID = 2<<8 | 20 // Repeatable. Deprecated.
+	IPTCKeywords                      IPTCID = 2<<8 | 25 // Repeatable.
+	IPTCSpecialInstructions           IPTCID = 2<<8 | 40
+	IPTCDateCreated                   IPTCID = 2<<8 | 55 // CCYYMMDD.
+	IPTCTimeCreated                   IPTCID = 2<<8 | 60 // HHMMSS±HHMM.
+	IPTCByline                        IPTCID = 2<<8 | 80 // Repeatable. Name of the creator.
+	IPTCBylineTitle                   IPTCID = 2<<8 | 85 // Repeatable. Job title of the creator.
+	IPTCCity                          IPTCID = 2<<8 | 90
+	IPTCSublocation                   IPTCID = 2<<8 | 92
+	IPTCProvinceState                 IPTCID = 2<<8 | 95
+	IPTCCountryCode                   IPTCID = 2<<8 | 100 // ISO 3166 code.
+	IPTCCountryName                   IPTCID = 2<<8 | 101
+	IPTCOriginalTransmissionReference IPTCID = 2<<8 | 103 // Job identifier.
+	IPTCHeadline                      IPTCID = 2<<8 | 105
+	IPTCCredit                        IPTCID = 2<<8 | 110
+	IPTCSource                        IPTCID = 2<<8 | 115
+	IPTCCopyrightNotice               IPTCID = 2<<8 | 116
+	IPTCContact                       IPTCID = 2<<8 | 118 // Repeatable.
+	IPTCCaption                       IPTCID = 2<<8 | 120 // Caption or abstract.
+	IPTCWriter                        IPTCID = 2<<8 | 122 // Repeatable. Writer of the caption.
+)
+
+var iptcName = map[IPTCID]string{
+	IPTCCodedCharacterSet:             "CodedCharacterSet",
+	IPTCRecordVersion:                 "RecordVersion",
+	IPTCObjectName:                    "ObjectName",
+	IPTCUrgency:                       "Urgency",
+	IPTCCategory:                      "Category",
+	IPTCSupplementalCategories:        "SupplementalCategories",
+	IPTCKeywords:                      "Keywords",
+	IPTCSpecialInstructions:           "SpecialInstructions",
+	IPTCDateCreated:                   "DateCreated",
+	IPTCTimeCreated:                   "TimeCreated",
+	IPTCByline:                        "By-line",
+	IPTCBylineTitle:                   "By-lineTitle",
+	IPTCCity:                          "City",
+	IPTCSublocation:                   "Sub-location",
+	IPTCProvinceState:                 "Province-State",
+	IPTCCountryCode:                   "Country-PrimaryLocationCode",
+	IPTCCountryName:                   "Country-PrimaryLocationName",
+	IPTCOriginalTransmissionReference: "OriginalTransmissionReference",
+	IPTCHeadline:                      "Headline",
+	IPTCCredit:                        "Credit",
+	IPTCSource:                        "Source",
+	IPTCCopyrightNotice:               "CopyrightNotice",
+	IPTCContact:                       "Contact",
+	IPTCCaption:                       "Caption-Abstract",
+	IPTCWriter:                        "Writer-Editor",
+}
+
+// iptcMaxLengths are the maximum lengths in bytes of datasets in IIM 4.2.
+var iptcMaxLengths = map[IPTCID]int{
+	IPTCCodedCharacterSet:             32,
+	IPTCRecordVersion:                 2,
+	IPTCObjectName:                    64,
+	IPTCUrgency:                       1,
+	IPTCCategory:                      3,
+	IPTCSupplementalCategories:        32,
+	IPTCKeywords:                      64,
+	IPTCSpecialInstructions:           256,
+	IPTCDateCreated:                   8,
+	IPTCTimeCreated:                   11,
+	IPTCByline:                        32,
+	IPTCBylineTitle:                   32,
+	IPTCCity:                          32,
+	IPTCSublocation:                   32,
+	IPTCProvinceState:                 32,
+	IPTCCountryCode:                   3,
+	IPTCCountryName:                   64,
+	IPTCOriginalTransmissionReference: 32,
+	IPTCHeadline:                      256,
+	IPTCCredit:                        32,
+	IPTCSource:                        32,
+	IPTCCopyrightNotice:               128,
+	IPTCContact:                       128,
+	IPTCCaption:                       2000,
+	IPTCWriter:                        32,
+}
+
+// Record returns the record number of the dataset.
+func (id IPTCID) Record() int {
+	return int(id >> 8)
+}
+
+// DataSet returns the dataset number in the record.
+func (id IPTCID) DataSet() int {
+	return int(id & 0xff)
+}
+
+func (id IPTCID) String() string {
+	if name, ok := iptcName[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d:%d", id.Record(), id.DataSet())
+}
+
+const (
+	iptcTagMarker    = 0x1c
+	iptcUTF8         = "\x1b%G"       // ISO 2022 escape sequence of UTF-8
+	photoshopIPTCNAA = 0x0404         // ID of the Photoshop image resource of IPTC-IIM records
+	photoshopMarker  = "8BIM"         // Signature of Photoshop image resources
+	iptcMaxLength    = 1<<15 - 1      // Maximum length of the data of datasets without extended length
+	iptcTimeLayout   = "150405-0700"  // Layout of TimeCreated
+	iptcDateLayout   = "20060102"     // Layout of DateCreated
+	iptcPadding      = "\x00\x00\x00" // IPTCs are padded to 4 bytes, as some writers store them as Long
+)
+
+// IPTCDataSet is a dataset of IPTC-IIM records, whose data are text unless noted otherwise.
+type IPTCDataSet struct {
+	ID   IPTCID
+	Data []byte
+}
+
+// IPTC are the datasets of IPTC-IIM records, in the order of the stream.
+//
+// Datasets of the application record are decoded as UTF-8 when CodedCharacterSet is ESC % G or when they are
+// valid UTF-8, and as ISO 8859-1 otherwise.
+type IPTC struct {
+	DataSets []IPTCDataSet
+}
+
+// IPTC decodes the IPTC-IIM records of the image, from TagIPTC or from the Photoshop image resources.
+//
+// It returns (nil, nil) when the image has no IPTC-IIM records.
+func (im *Image) IPTC() (*IPTC, error) {
+	var data []byte
+	if tag := im.Tag[TagIPTC]; tag != nil {
+		data = tag.Data
+	} else if tag := im.Tag[TagPhotoshop]; tag != nil {
+		var err error
+		data, err = photoshopResource(tag.Data, photoshopIPTCNAA)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return ParseIPTC(data)
+}
+
+// SetIPTC sets TagIPTC to the encoded datasets of iptc.
+func (im *Image) SetIPTC(iptc *IPTC) error {
+	data, err := iptc.Encode()
+	if err != nil {
+		return err
+	}
+	return im.SetTag(TagIPTC, TagTypeUndefined, data)
+}
+
+// photoshopResource returns the data of the Photoshop image resource with given ID, or nil if it is missing.
+func photoshopResource(data []byte, id uint16) ([]byte, error) {
+	for len(data) >= 4 && string(data[:4]) == photoshopMarker {
+		// Signature, ID, name as a Pascal string padded to an even size, size of data, data padded to an even size
+		if len(data) < 7 {
+			return nil, FormatError("Photoshop image resources: truncated resource")
+		}
+		resourceID := binary.BigEndian.Uint16(data[4:6])
+		nameSize := (1 + int(data[6]) + 1) &^ 1
+		if len(data) < 6+nameSize+4 {
+			return nil, FormatError("Photoshop image resources: truncated resource")
+		}
+		size := int(binary.BigEndian.Uint32(data[6+nameSize:]))
+		data = data[6+nameSize+4:]
+		if size < 0 || size > len(data) {
+			return nil, FormatError("Photoshop image resources: truncated resource")
+		}
+		if resourceID == id {
+			return data[:size], nil
+		}
+		data = data[(size+1)&^1:]
+	}
+	return nil, nil
+}
+
+// ParseIPTC decodes a stream of IPTC-IIM datasets. Padding zeros after the last dataset are ignored.
+func ParseIPTC(data []byte) (*IPTC, error) {
+	iptc := &IPTC{}
+	for len(data) > 0 && data[0] == iptcTagMarker {
+		if len(data) < 5 {
+			return nil, FormatError("IPTC: truncated dataset")
+		}
+		id := IPTCID(data[1])<<8 | IPTCID(data[2])
+		length := int(binary.BigEndian.Uint16(data[3:5]))
+		data = data[5:]
+		if length > iptcMaxLength {
+			// Extended dataset, whose length is stored in the next bytes
+			n := length & iptcMaxLength
+			if n > 4 || len(data) < n {
+				return nil, FormatError(fmt.Sprintf("IPTC: invalid extended length of dataset %d:%d", id.Record(), id.DataSet()))
+			}
+			length = 0
+			for _, b := range data[:n] {
+				length = length<<8 | int(b)
+			}
+			data = data[n:]
+		}
+		if length < 0 || length > len(data) {
+			return nil, FormatError(fmt.Sprintf("IPTC: truncated dataset %d:%d", id.Record(), id.DataSet()))
+		}
+		iptc.DataSets = append(iptc.DataSets, IPTCDataSet{ID: id, Data: data[:length:length]})
+		data = data[length:]
+	}
+	if len(bytes.Trim(data, "\x00")) > 0 {
+		return nil, FormatError("IPTC: invalid tag marker")
+	}
+	return iptc, nil
+}
+
+// Encode returns the stream of datasets, sorted by record and padded to a multiple of 4 bytes.
+// RecordVersion is moved to the start of the application record.
+//
+// Only datasets of the object data record (8) can be longer than 32767 bytes, with an extended length.
+func (iptc *IPTC) Encode() ([]byte, error) {
+	dataSets := append([]IPTCDataSet{}, iptc.DataSets...)
+	sort.SliceStable(dataSets, func(i, j int) bool {
+		if ri, rj := dataSets[i].ID.Record(), dataSets[j].ID.Record(); ri != rj {
+			return ri < rj
+		}
+		return dataSets[i].ID == IPTCRecordVersion && dataSets[j].ID != IPTCRecordVersion
+	})
+	b := &bytes.Buffer{}
+	for _, ds := range dataSets {
+		b.Write([]byte{iptcTagMarker, byte(ds.ID.Record()), byte(ds.ID.DataSet())})
+		if len(ds.Data) > iptcMaxLength {
+			if ds.ID.Record() != 8 {
+				return nil, fmt.Errorf("IPTC: dataset %v of %d bytes exceeds %d bytes", ds.ID, len(ds.Data), iptcMaxLength)
+			}
+			b.Write([]byte{0x80, 4})
+			binary.Write(b, binary.BigEndian, uint32(len(ds.Data)))
+		} else {
+			binary.Write(b, binary.BigEndian, uint16(len(ds.Data)))
+		}
+		b.Write(ds.Data)
+	}
+	b.WriteString(iptcPadding[:(4-b.Len()%4)%4])
+	return b.Bytes(), nil
+}
+
+// isUTF8 reports whether CodedCharacterSet declares UTF-8.
+func (iptc *IPTC) isUTF8() bool {
+	for _, ds := range iptc.DataSets {
+		if ds.ID == IPTCCodedCharacterSet {
+			return string(ds.Data) == iptcUTF8
+		}
+	}
+	return false
+}
+
+// decodeText decodes the text of a dataset.
+func (iptc *IPTC) decodeText(data []byte) string {
+	if iptc.isUTF8() || utf8.Valid(data) {
+		return string(data)
+	}
+	// ISO 8859-1, whose code points are the same in Unicode
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// Strings returns the text of all datasets with given ID, e.g. for repeatable datasets such as IPTCKeywords.
+func (iptc *IPTC) Strings(id IPTCID) []string {
+	if iptc == nil {
+		return nil
+	}
+	var values []string
+	for _, ds := range iptc.DataSets {
+		if ds.ID == id {
+			values = append(values, strings.TrimRight(iptc.decodeText(ds.Data), "\x00"))
+		}
+	}
+	return values
+}
+
+// String returns the text of the first dataset with given ID.
+func (iptc *IPTC) String(id IPTCID) (string, bool) {
+	values := iptc.Strings(id)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// setUTF8 declares UTF-8 in CodedCharacterSet, converting the text of the application record to UTF-8.
+func (iptc *IPTC) setUTF8() {
+	if iptc.isUTF8() {
+		return
+	}
+	for i, ds := range iptc.DataSets {
+		if ds.ID.Record() == 2 && ds.ID != IPTCRecordVersion {
+			iptc.DataSets[i].Data = []byte(iptc.decodeText(ds.Data))
+		}
+	}
+	iptc.delete(IPTCCodedCharacterSet)
+	iptc.DataSets = append([]IPTCDataSet{{ID: IPTCCodedCharacterSet, Data: []byte(iptcUTF8)}}, iptc.DataSets...)
+}
+
+// delete removes all datasets with given ID.
+func (iptc *IPTC) delete(id IPTCID) {
+	dataSets := iptc.DataSets[:0]
+	for _, ds := range iptc.DataSets {
+		if ds.ID != id {
+			dataSets = append(dataSets, ds)
+		}
+	}
+	iptc.DataSets = dataSets
+}
+
+// Set replaces the datasets with given ID by one dataset for each value, or removes them without values.
+//
+// Values are written in UTF-8, which is declared in CodedCharacterSet, and RecordVersion is set to 4
+// at the start of the application record when it is missing. It returns an error, without changing iptc,
+// if a value is longer than the maximum length of the dataset in IIM 4.2, e.g. 256 bytes for Headline.
+func (iptc *IPTC) Set(id IPTCID, values ...string) error {
+	maxLength := iptcMaxLength
+	if n, ok := iptcMaxLengths[id]; ok {
+		maxLength = n
+	}
+	for _, v := range values {
+		if len(v) > maxLength && id.Record() != 8 {
+			return fmt.Errorf("IPTC: value of %d bytes exceeds the maximum length of %v, %d bytes", len(v), id, maxLength)
+		}
+	}
+
+	iptc.setUTF8()
+	iptc.delete(id)
+	if len(values) == 0 {
+		return nil
+	}
+	if id.Record() == 2 && id != IPTCRecordVersion {
+		if _, ok := iptc.String(IPTCRecordVersion); !ok {
+			// RecordVersion is the first dataset of the record
+			i := 0
+			for i < len(iptc.DataSets) && iptc.DataSets[i].ID.Record() < 2 {
+				i++
+			}
+			iptc.DataSets = append(iptc.DataSets, IPTCDataSet{})
+			copy(iptc.DataSets[i+1:], iptc.DataSets[i:])
+			iptc.DataSets[i] = IPTCDataSet{ID: IPTCRecordVersion, Data: []byte{0, 4}}
+		}
+	}
+	for _, v := range values {
+		iptc.DataSets = append(iptc.DataSets, IPTCDataSet{ID: id, Data: []byte(v)})
+	}
+	return nil
+}
+
+// Caption returns Caption-Abstract, the description of the image.
+func (iptc *IPTC) Caption() (string, bool) {
+	return iptc.String(IPTCCaption)
+}
+
+// Headline returns Headline, a summary of the caption.
+func (iptc *IPTC) Headline() (string, bool) {
+	return iptc.String(IPTCHeadline)
+}
+
+// ObjectName returns ObjectName, the title of the image.
+func (iptc *IPTC) ObjectName() (string, bool) {
+	return iptc.String(IPTCObjectName)
+}
+
+// Keywords returns all Keywords datasets.
+func (iptc *IPTC) Keywords() []string {
+	return iptc.Strings(IPTCKeywords)
+}
+
+// Bylines returns all By-line datasets, the names of the creators.
+func (iptc *IPTC) Bylines() []string {
+	return iptc.Strings(IPTCByline)
+}
+
+// City returns City.
+func (iptc *IPTC) City() (string, bool) {
+	return iptc.String(IPTCCity)
+}
+
+// ProvinceState returns Province-State.
+func (iptc *IPTC) ProvinceState() (string, bool) {
+	return iptc.String(IPTCProvinceState)
+}
+
+// Country returns Country-PrimaryLocationName.
+func (iptc *IPTC) Country() (string, bool) {
+	return iptc.String(IPTCCountryName)
+}
+
+// Credit returns Credit, the provider of the image.
+func (iptc *IPTC) Credit() (string, bool) {
+	return iptc.String(IPTCCredit)
+}
+
+// Source returns Source, the owner of the image.
+func (iptc *IPTC) Source() (string, bool) {
+	return iptc.String(IPTCSource)
+}
+
+// CopyrightNotice returns CopyrightNotice.
+func (iptc *IPTC) CopyrightNotice() (string, bool) {
+	return iptc.String(IPTCCopyrightNotice)
+}
+
+// DateCreated returns the date of DateCreated and TimeCreated. Without TimeCreated, the time is midnight in UTC.
+func (iptc *IPTC) DateCreated() (time.Time, bool) {
+	date, ok := iptc.String(IPTCDateCreated)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(iptcDateLayout, date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if s, ok := iptc.String(IPTCTimeCreated); ok {
+		if clock, err := time.Parse(iptcTimeLayout, s); err == nil {
+			t = time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, clock.Location())
+		}
+	}
+	return t, true
+}
+
+// SetDateCreated sets DateCreated and TimeCreated to t, to the second.
+// It returns an error for years after 9999.
+func (iptc *IPTC) SetDateCreated(t time.Time) error {
+	if err := iptc.Set(IPTCDateCreated, t.Format(iptcDateLayout)); err != nil {
+		return err
+	}
+	return iptc.Set(IPTCTimeCreated, t.Format(iptcTimeLayout))
+}
@@ -0,0 +1,179 @@
+package tiff_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestImage_SetIPTC(t *testing.T) {
+	// Latin-1 caption without CodedCharacterSet, converted to UTF-8 by Set
+	iptc, err := tiff.ParseIPTC([]byte("\x1c\x02\x78\x00\x0cCaf\xe9 du port\x00\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := iptc.Caption(); !ok || v != "Café du port" {
+		t.Errorf("Latin-1 Caption = %q, %v", v, ok)
+	}
+	for id, values := range map[tiff.IPTCID][]string{
+		tiff.IPTCKeywords: {"port", "Genève", "harbour"},
+		tiff.IPTCByline:   {"Jane Doe"},
+		tiff.IPTCCity:     {"Genève"},
+		tiff.IPTCHeadline: {strings.Repeat("h", 256)},
+	} {
+		if err := iptc.Set(id, values...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	date := time.Date(2021, 7, 14, 10, 30, 15, 0, time.FixedZone("", 2*3600))
+	if err := iptc.SetDateCreated(date); err != nil {
+		t.Fatal(err)
+	}
+
+	// Maximum lengths of IIM 4.2
+	if err := iptc.Set(tiff.IPTCHeadline, strings.Repeat("h", 257)); err == nil {
+		t.Errorf("no error for Headline of 257 bytes")
+	}
+	if err := iptc.Set(tiff.IPTCKeywords, strings.Repeat("é", 33)); err == nil {
+		t.Errorf("no error for Keywords of 66 bytes")
+	}
+
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	if err := im.SetIPTC(iptc); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.EncodeImage(make([]uint8, 2*2)); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag := ims[0].Tag[tiff.TagIPTC]; tag == nil || tag.Count%4 != 0 {
+		t.Fatalf("IPTC tag = %v", tag)
+	}
+	got, err := ims[0].IPTC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := got.DataSets[0]; first.ID != tiff.IPTCCodedCharacterSet || string(first.Data) != "\x1b%G" {
+		t.Errorf("first dataset = %v %q", first.ID, first.Data)
+	}
+	// RecordVersion was missing, and starts the application record before the existing Caption
+	if second := got.DataSets[1]; second.ID != tiff.IPTCRecordVersion {
+		t.Errorf("second dataset = %v, want RecordVersion", second.ID)
+	}
+	if v, ok := got.String(tiff.IPTCRecordVersion); !ok || v != "\x00\x04" {
+		t.Errorf("RecordVersion = %q, %v", v, ok)
+	}
+	if v, ok := got.Caption(); !ok || v != "Café du port" {
+		t.Errorf("Caption = %q, %v", v, ok)
+	}
+	if v := got.Keywords(); !reflect.DeepEqual(v, []string{"port", "Genève", "harbour"}) {
+		t.Errorf("Keywords = %q", v)
+	}
+	if v := got.Bylines(); !reflect.DeepEqual(v, []string{"Jane Doe"}) {
+		t.Errorf("Bylines = %q", v)
+	}
+	if v, ok := got.City(); !ok || v != "Genève" {
+		t.Errorf("City = %q, %v", v, ok)
+	}
+	if v, ok := got.Headline(); !ok || len(v) != 256 {
+		t.Errorf("Headline of %d bytes, %v", len(v), ok)
+	}
+	if v, ok := got.DateCreated(); !ok || !v.Equal(date) {
+		t.Errorf("DateCreated = %v, %v", v, ok)
+	}
+	if _, ok := got.Credit(); ok {
+		t.Errorf("Credit found")
+	}
+	if v := tiff.IPTCCity.String(); v != "City" {
+		t.Errorf("IPTCCity.String() = %q", v)
+	}
+	if v := tiff.IPTCID(2<<8 | 200).String(); v != "2:200" {
+		t.Errorf("IPTCID(2:200).String() = %q", v)
+	}
+}
+
+func TestImage_IPTC_Photoshop(t *testing.T) {
+	records := []byte("\x1c\x01\x5a\x00\x03\x1b%G\x1c\x02\x19\x00\x04news\x1c\x02\x19\x00\x05sport")
+	resources := []byte("8BIM\x03\xed\x00\x00\x00\x00\x00\x03abc\x00")
+	resources = append(resources, "8BIM\x04\x04\x03IIM\x00\x00\x00"...)
+	resources = append(resources, byte(len(records)))
+	resources = append(resources, records...)
+
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	im.SetTag(tiff.TagPhotoshop, tiff.TagTypeByte, resources)
+	if err := im.EncodeImage(make([]uint8, 2*2)); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ims[0].IPTC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := got.Keywords(); !reflect.DeepEqual(v, []string{"news", "sport"}) {
+		t.Errorf("Keywords = %q", v)
+	}
+
+	if _, err := tiff.ParseIPTC([]byte("\x1c\x02\x19\x00\x10short")); err == nil {
+		t.Errorf("no error for truncated dataset")
+	}
+}
+
+func TestIPTC_Encode(t *testing.T) {
+	// RecordVersion is written first in the application record
+	iptc := &tiff.IPTC{DataSets: []tiff.IPTCDataSet{
+		{ID: tiff.IPTCKeywords, Data: []byte("news")},
+		{ID: tiff.IPTCRecordVersion, Data: []byte{0, 4}},
+		{ID: tiff.IPTCCodedCharacterSet, Data: []byte("\x1b%G")},
+	}}
+	data, err := iptc.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1c\x01\x5a\x00\x03\x1b%G\x1c\x02\x00\x00\x02\x00\x04\x1c\x02\x19\x00\x04news"
+	if string(data) != want {
+		t.Errorf("Encode = %q, want %q", data, want)
+	}
+
+	// Extended lengths are only allowed in the object data record
+	objectData := tiff.IPTCDataSet{ID: tiff.IPTCID(8<<8 | 10), Data: bytes.Repeat([]byte{1}, 40000)}
+	iptc = &tiff.IPTC{DataSets: []tiff.IPTCDataSet{objectData}}
+	data, err = iptc.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tiff.ParseIPTC(data); err != nil || !reflect.DeepEqual(got.DataSets, []tiff.IPTCDataSet{objectData}) {
+		t.Errorf("ParseIPTC of extended dataset: %v", err)
+	}
+	iptc = &tiff.IPTC{DataSets: []tiff.IPTCDataSet{{ID: tiff.IPTCCaption, Data: make([]byte, 40000)}}}
+	if _, err := iptc.Encode(); err == nil {
+		t.Errorf("no error for extended dataset in the application record")
+	}
+}
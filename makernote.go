@@ -0,0 +1,315 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Camera vendors of maker notes
+const (
+	VendorCanon     = "Canon"
+	VendorFujifilm  = "Fujifilm"
+	VendorNikon     = "Nikon"
+	VendorOlympus   = "Olympus"
+	VendorPanasonic = "Panasonic"
+	VendorPentax    = "Pentax"
+	VendorSony      = "Sony"
+)
+
+// MakerNote is the vendor-specific IFD stored in the MakerNote tag of the Exif IFD.
+//
+// IDs of tags are specific to the vendor, and named by TagName. Offsets of tags are relative to Base.
+type MakerNote struct {
+	Vendor string
+	Header *Header // Byte order of the maker note
+	Base   int64   // Offset in the file to which offsets in the maker note are relative
+	Tag    map[TagID]*Tag
+}
+
+// makerNoteTagName are names of common tags of maker notes by vendor.
+var makerNoteTagName = map[string]map[TagID]string{
+	VendorCanon: {
+		0x0001: "CameraSettings",
+		0x0002: "FocalLength",
+		0x0004: "ShotInfo",
+		0x0006: "ImageType",
+		0x0007: "FirmwareVersion",
+		0x0008: "FileNumber",
+		0x0009: "OwnerName",
+		0x000c: "SerialNumber",
+		0x0010: "ModelID",
+		0x0095: "LensModel",
+		0x0096: "InternalSerialNumber",
+	},
+	VendorFujifilm: {
+		0x0000: "Version",
+		0x0010: "InternalSerialNumber",
+		0x1000: "Quality",
+		0x1001: "Sharpness",
+		0x1002: "WhiteBalance",
+		0x1003: "Saturation",
+		0x1401: "DynamicRange",
+		0x1404: "MinFocalLength",
+		0x1405: "MaxFocalLength",
+		0x1438: "ImageCount",
+	},
+	VendorNikon: {
+		0x0001: "MakerNoteVersion",
+		0x0002: "ISO",
+		0x0004: "Quality",
+		0x0005: "WhiteBalance",
+		0x001d: "SerialNumber",
+		0x0083: "LensType",
+		0x0084: "Lens",
+		0x0098: "LensData",
+		0x00a7: "ShutterCount",
+		0x00ab: "VariProgram",
+	},
+	VendorOlympus: {
+		0x0200: "SpecialMode",
+		0x0207: "CameraType",
+		0x0209: "CameraID",
+		0x2010: "Equipment",
+		0x2020: "CameraSettings",
+		0x2030: "RawDevelopment",
+		0x2040: "ImageProcessing",
+		0x2050: "FocusInfo",
+	},
+	VendorPanasonic: {
+		0x0001: "ImageQuality",
+		0x0002: "FirmwareVersion",
+		0x0003: "WhiteBalance",
+		0x0025: "InternalSerialNumber",
+		0x0051: "LensType",
+		0x0052: "LensSerialNumber",
+		0x0053: "AccessoryType",
+	},
+	VendorPentax: {
+		0x0000: "PentaxVersion",
+		0x0005: "PentaxModelID",
+		0x0029: "FrameNumber",
+		0x003f: "LensRec",
+		0x005d: "ShutterCount",
+		0x0207: "LensInfo",
+	},
+	VendorSony: {
+		0x0102: "Quality",
+		0x0104: "FlashExposureComp",
+		0x2010: "Tag2010",
+		0x9050: "Tag9050",
+		0xb000: "FileFormat",
+		0xb001: "SonyModelID",
+		0xb027: "LensType",
+	},
+}
+
+// TagName returns the name of a tag of the maker note, or its ID in hexadecimal if it is unknown.
+func (mn *MakerNote) TagName(id TagID) string {
+	if name, ok := makerNoteTagName[mn.Vendor][id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", uint16(id))
+}
+
+// makerNoteVendor returns the vendor of a camera from TagMake.
+func makerNoteVendor(cameraMake string) string {
+	cameraMake = strings.ToUpper(strings.TrimSpace(cameraMake))
+	for _, v := range []struct{ prefix, vendor string }{
+		{"CANON", VendorCanon},
+		{"FUJIFILM", VendorFujifilm},
+		{"NIKON", VendorNikon},
+		{"OLYMPUS", VendorOlympus},
+		{"OM DIGITAL", VendorOlympus},
+		{"PANASONIC", VendorPanasonic},
+		{"PENTAX", VendorPentax},
+		{"RICOH", VendorPentax},
+		{"SONY", VendorSony},
+	} {
+		if strings.HasPrefix(cameraMake, v.prefix) {
+			return v.vendor
+		}
+	}
+	return ""
+}
+
+// byteOrderMark returns the byte order of "II" or "MM", or def otherwise.
+func byteOrderMark(mark []byte, def binary.ByteOrder) binary.ByteOrder {
+	switch string(mark) {
+	case "II":
+		return binary.LittleEndian
+	case "MM":
+		return binary.BigEndian
+	default:
+		return def
+	}
+}
+
+// MakerNote decodes the maker note of a decoded image, whose vendor is detected from the header of the note
+// and from TagMake.
+//
+// It returns (nil, nil) when the image has no maker note.
+func (im *Image) MakerNote() (*MakerNote, error) {
+	if im.Exif == nil || im.Exif.Tag[ExifTagMakerNote] == nil {
+		return nil, nil
+	}
+	if im.rd == nil {
+		return nil, fmt.Errorf("maker note of an image which is not decoded")
+	}
+	tag := im.Exif.Tag[ExifTagMakerNote]
+	note, offset := tag.Data, tag.OffsetData
+	cameraMake, _ := im.Tag[TagMake].String()
+	fileOrder := im.Header.ByteOrder
+
+	//
+	// Find the vendor, the byte order, the offset of the IFD and the base of offsets
+	//
+	mn := &MakerNote{Vendor: makerNoteVendor(cameraMake)}
+	byteOrder := fileOrder
+	var start int64   // Offset of the IFD in the note
+	var relative bool // Offsets are relative to the note, from base
+	var base int64    // Offset of the base in the note
+	var embedded bool // The IFD is preceded by a TIFF header at base
+	switch {
+	case bytes.HasPrefix(note, []byte("Nikon\x00\x02")):
+		mn.Vendor = VendorNikon
+		relative, base, embedded = true, 10, true
+	case bytes.HasPrefix(note, []byte("Nikon\x00\x01")):
+		mn.Vendor = VendorNikon
+		start = 8
+	case bytes.HasPrefix(note, []byte("SONY DSC \x00\x00\x00")), bytes.HasPrefix(note, []byte("SONY CAM \x00\x00\x00")):
+		mn.Vendor = VendorSony
+		start = 12
+	case bytes.HasPrefix(note, []byte("OLYMPUS\x00")) && len(note) >= 12:
+		mn.Vendor = VendorOlympus
+		byteOrder = byteOrderMark(note[8:10], fileOrder)
+		start, relative = 12, true
+	case bytes.HasPrefix(note, []byte("OM SYSTEM\x00")) && len(note) >= 16:
+		mn.Vendor = VendorOlympus
+		byteOrder = byteOrderMark(note[12:14], fileOrder)
+		start, relative = 16, true
+	case bytes.HasPrefix(note, []byte("OLYMP\x00")):
+		mn.Vendor = VendorOlympus
+		start = 8
+	case bytes.HasPrefix(note, []byte("FUJIFILM")) && len(note) >= 12:
+		mn.Vendor = VendorFujifilm
+		byteOrder = binary.LittleEndian
+		start, relative = int64(binary.LittleEndian.Uint32(note[8:12])), true
+	case bytes.HasPrefix(note, []byte("AOC\x00")) && len(note) >= 6:
+		mn.Vendor = VendorPentax
+		byteOrder = byteOrderMark(note[4:6], fileOrder)
+		start = 6
+	case bytes.HasPrefix(note, []byte("PENTAX \x00")) && len(note) >= 10:
+		mn.Vendor = VendorPentax
+		byteOrder = byteOrderMark(note[8:10], fileOrder)
+		start, relative = 10, true
+	case bytes.HasPrefix(note, []byte("Panasonic\x00\x00\x00")):
+		mn.Vendor = VendorPanasonic
+		start = 12
+	case mn.Vendor == VendorCanon, mn.Vendor == VendorNikon, mn.Vendor == VendorSony:
+		// IFD without header
+	default:
+		return nil, UnsupportedError(fmt.Sprintf("maker note of %q", cameraMake))
+	}
+
+	//
+	// Decode the IFD with its own byte order, and offsets relative to the base
+	//
+	// The data of tags are within the note, which bounds their size before allocating them
+	d := &Decoder{
+		Header:      &Header{ByteOrder: byteOrder, Version: VersionClassicTIFF},
+		r:           im.rd,
+		maxDataSize: int64(len(note)),
+	}
+	if relative {
+		if base >= int64(len(note)) {
+			return nil, FormatError("maker note is too short")
+		}
+		mn.Base = offset + base
+		d.r = io.NewSectionReader(im.rd, mn.Base, int64(len(note))-base)
+		d.maxDataSize = int64(len(note)) - base
+		if embedded {
+			var err error
+			d, err = NewDecoder(d.r)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode maker note: %s", err)
+			}
+			start = d.Header.OffsetFirstIFD
+		}
+	} else {
+		start += offset
+	}
+	ifd, err := d.DecodeIFD(start)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode maker note: %s", err)
+	}
+	mn.Header = d.Header
+	mn.Tag = ifd.Tag
+	return mn, nil
+}
+
+// LensID returns the vendor-specific lens identifier of Canon (LensType of CameraSettings), Sony (LensType)
+// and Pentax (LensRec, as series * 256 + lens) maker notes.
+func (mn *MakerNote) LensID() (int, bool) {
+	switch mn.Vendor {
+	case VendorCanon:
+		settings, ok := mn.Tag[0x0001].UintSlice()
+		if !ok || len(settings) <= 22 {
+			return 0, false
+		}
+		return int(settings[22]), true
+	case VendorSony:
+		v, ok := mn.Tag[0xb027].Uint()
+		return int(v), ok
+	case VendorPentax:
+		v, ok := mn.Tag[0x003f].Value().Bytes()
+		if !ok || len(v) < 2 {
+			return 0, false
+		}
+		return int(v[0])<<8 | int(v[1]), true
+	default:
+		return 0, false
+	}
+}
+
+// LensModel returns the lens model of Canon (LensModel) and Panasonic (LensType) maker notes,
+// or the focal lengths and apertures of Nikon maker notes (Lens).
+func (mn *MakerNote) LensModel() (string, bool) {
+	switch mn.Vendor {
+	case VendorCanon:
+		return mn.Tag[0x0095].String()
+	case VendorPanasonic:
+		return mn.Tag[0x0051].String()
+	case VendorNikon:
+		v, ok := mn.Tag[0x0084].Value().RationalSlice()
+		if !ok || len(v) != 4 {
+			return "", false
+		}
+		format := func(min, max Rational) string {
+			if min == max {
+				return fmt.Sprintf("%g", min.Float64())
+			}
+			return fmt.Sprintf("%g-%g", min.Float64(), max.Float64())
+		}
+		return fmt.Sprintf("%smm f/%s", format(v[0], v[1]), format(v[2], v[3])), true
+	default:
+		return "", false
+	}
+}
+
+// ShutterCount returns the number of shutter actuations of Nikon maker notes (ShutterCount),
+// or the number of images of Fujifilm maker notes (ImageCount).
+func (mn *MakerNote) ShutterCount() (int, bool) {
+	switch mn.Vendor {
+	case VendorNikon:
+		v, ok := mn.Tag[0x00a7].Uint()
+		return int(v), ok
+	case VendorFujifilm:
+		v, ok := mn.Tag[0x1438].Uint()
+		return int(v), ok
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,143 @@
+package tiff_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+// makerNoteEntry is an IFD entry of a maker note built by makerNoteIFD.
+type makerNoteEntry struct {
+	id      uint16
+	tagType tiff.TagType
+	count   uint32
+	data    []byte
+}
+
+// makerNoteIFD returns an IFD at offset ifdOffset whose data larger than 4 bytes follow the IFD.
+// Offsets written in the IFD are relative to base.
+func makerNoteIFD(byteOrder binary.ByteOrder, base int64, ifdOffset int64, entries []makerNoteEntry) []byte {
+	buf := make([]byte, 2+12*len(entries)+4)
+	byteOrder.PutUint16(buf, uint16(len(entries)))
+	for i, e := range entries {
+		entry := buf[2+12*i:]
+		byteOrder.PutUint16(entry[0:], e.id)
+		byteOrder.PutUint16(entry[2:], uint16(e.tagType))
+		byteOrder.PutUint32(entry[4:], e.count)
+		if len(e.data) <= 4 {
+			copy(entry[8:12], e.data)
+			continue
+		}
+		byteOrder.PutUint32(entry[8:], uint32(ifdOffset+int64(len(buf))-base))
+		buf = append(buf, e.data...)
+	}
+	return buf
+}
+
+// encodeMakerNote encodes an image with a maker note and returns the decoded image.
+func encodeMakerNote(t *testing.T, cameraMake string, note []byte) *tiff.Image {
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	im.SetTag(tiff.TagMake, tiff.TagTypeASCII, cameraMake)
+	if err := im.SetExifTag(tiff.ExifTagMakerNote, tiff.TagTypeUndefined, note); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.EncodeImage(make([]uint8, 2*2)); err != nil {
+		t.Fatal(err)
+	}
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ims[0]
+}
+
+func TestImage_MakerNote_Nikon(t *testing.T) {
+	// Nikon type 3: header, then a big-endian TIFF with offsets relative to it
+	lens := make([]byte, 32)
+	for i, v := range []uint32{24, 1, 70, 1, 28, 10, 28, 10} {
+		binary.BigEndian.PutUint32(lens[4*i:], v)
+	}
+	note := append([]byte("Nikon\x00\x02\x10\x00\x00MM\x00\x2a\x00\x00\x00\x08"), makerNoteIFD(binary.BigEndian, 0, 8, []makerNoteEntry{
+		{id: 0x0084, tagType: tiff.TagTypeRational, count: 4, data: lens},
+		{id: 0x00a7, tagType: tiff.TagTypeLong, count: 1, data: []byte{0, 0, 0x30, 0x39}},
+	})...)
+
+	mn, err := encodeMakerNote(t, "NIKON CORPORATION", note).MakerNote()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mn.Vendor != tiff.VendorNikon || mn.Header.ByteOrder != binary.BigEndian {
+		t.Errorf("Vendor = %s, ByteOrder = %v", mn.Vendor, mn.Header.ByteOrder)
+	}
+	if v, ok := mn.ShutterCount(); !ok || v != 12345 {
+		t.Errorf("ShutterCount = %d, %v", v, ok)
+	}
+	if v, ok := mn.LensModel(); !ok || v != "24-70mm f/2.8" {
+		t.Errorf("LensModel = %q, %v", v, ok)
+	}
+	if v := mn.TagName(0x00a7); v != "ShutterCount" {
+		t.Errorf("TagName(0x00a7) = %q", v)
+	}
+	if v := mn.TagName(0x1234); v != "0x1234" {
+		t.Errorf("TagName(0x1234) = %q", v)
+	}
+}
+
+func TestImage_MakerNote_Canon(t *testing.T) {
+	// Canon: IFD without header, with offsets relative to the file
+	settings := make([]byte, 2*23)
+	binary.LittleEndian.PutUint16(settings[2*22:], 61182)
+	lensModel := []byte("RF24-70mm F2.8 L IS USM\x00")
+	build := func(offset int64) []byte {
+		return makerNoteIFD(binary.LittleEndian, -offset, 0, []makerNoteEntry{
+			{id: 0x0001, tagType: tiff.TagTypeShort, count: 23, data: settings},
+			{id: 0x0095, tagType: tiff.TagTypeASCII, count: uint32(len(lensModel)), data: lensModel},
+		})
+	}
+	// The offset of the note is known after encoding it once
+	im := encodeMakerNote(t, "Canon", build(0))
+	im = encodeMakerNote(t, "Canon", build(im.Exif.Tag[tiff.ExifTagMakerNote].OffsetData))
+
+	mn, err := im.MakerNote()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mn.Vendor != tiff.VendorCanon {
+		t.Errorf("Vendor = %s", mn.Vendor)
+	}
+	if v, ok := mn.LensID(); !ok || v != 61182 {
+		t.Errorf("LensID = %d, %v", v, ok)
+	}
+	if v, ok := mn.LensModel(); !ok || v != "RF24-70mm F2.8 L IS USM" {
+		t.Errorf("LensModel = %q, %v", v, ok)
+	}
+	if _, ok := mn.ShutterCount(); ok {
+		t.Errorf("ShutterCount found")
+	}
+}
+
+func TestImage_MakerNote_Unsupported(t *testing.T) {
+	im := encodeMakerNote(t, "Unknown", []byte("unknown maker note"))
+	if _, err := im.MakerNote(); err == nil {
+		t.Errorf("no error for unknown maker note")
+	}
+
+	// A count larger than the note is rejected before allocating the data of the tag
+	note := append([]byte("SONY DSC \x00\x00\x00"), makerNoteIFD(binary.LittleEndian, 0, 12, []makerNoteEntry{
+		{id: 0xb027, tagType: tiff.TagTypeLong, count: 0x40000000, data: []byte{0, 0, 0, 0}},
+	})...)
+	im = encodeMakerNote(t, "SONY", note)
+	if _, err := im.MakerNote(); err == nil {
+		t.Errorf("no error for tag data larger than the maker note")
+	}
+}
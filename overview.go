@@ -0,0 +1,339 @@
+package tiff
+
+import (
+	"math"
+)
+
+// Resampling is the method to compute pixels of overviews.
+type Resampling int
+
+const (
+	ResamplingNearest Resampling = iota // Top-left pixel of each 2×2 block
+	ResamplingAverage                   // Mean of each 2×2 block
+	ResamplingMode                      // Most frequent pixel of each 2×2 block, e.g. for palette images or classes
+	ResamplingLanczos                   // Lanczos filter with 3 lobes
+)
+
+// OverviewOptions are options for building overviews of an image when it is encoded with EncodeImage.
+type OverviewOptions struct {
+	// Levels is the number of overviews, each half the width and height of the previous level.
+	// If Levels is 0, overviews are added until the smallest one fits in a single tile,
+	// or in 256×256 pixels for stripped images.
+	Levels int
+
+	Resampling Resampling
+
+	// Compression is the compression of each overview, starting from the largest one.
+	// Overviews beyond the end of Compression use the compression of the image.
+	Compression []int
+
+	// SubIFDs writes overviews as SubIFDs of the image. Otherwise, overviews are written as the IFDs
+	// following the image, with NewSubfileType of NewFiletypeReducedImage.
+	SubIFDs bool
+}
+
+// SetOverviews makes EncodeImage build and encode overviews after the image.
+//
+// Overviews have the same pixel format and tile size or RowsPerStrip as the image.
+// They are built from the full image data, so NewRowWriter and NewTileWriter return an error for images with overviews.
+func (im *Image) SetOverviews(opts *OverviewOptions) {
+	im.overviews = opts
+}
+
+// levels returns the number of overviews of an image.
+func (opts *OverviewOptions) levels(l *segmentLayout) int {
+	if opts.Levels > 0 {
+		return opts.Levels
+	}
+	maxWidth, maxHeight := 256, 256
+	if l.tiled {
+		maxWidth, maxHeight = l.segmentWidth, l.segmentHeight
+	}
+	levels := 0
+	for width, height := l.width, l.height; width > maxWidth || height > maxHeight; levels++ {
+		width, height = (width+1)/2, (height+1)/2
+	}
+	return levels
+}
+
+// overviewTagIDs are the tags copied from an image to its overviews.
+var overviewTagIDs = []TagID{
+	TagBitsPerSample,
+	TagPhotometric,
+	TagSamplesPerPixel,
+	TagRowsPerStrip,
+	TagPlanarConfig,
+	TagColorMap,
+	TagTileWidth,
+	TagTileLength,
+	TagExtraSamples,
+	TagSampleFormat,
+}
+
+// newOverview sets the tags of an overview of the image, at given level starting from 0.
+func (im *Image) newOverview(ov *Image, level int, width, height int) {
+	if ov.Tag == nil {
+		ov.Tag = make(map[TagID]*Tag)
+	}
+	for _, id := range overviewTagIDs {
+		if tag := im.Tag[id]; tag != nil {
+			ov.Tag[id] = tag
+		}
+	}
+	ov.SetTag(TagNewSubfileType, TagTypeLong, uint32(NewFiletypeReducedImage))
+	ov.SetWidthHeight(width, height)
+	compression := im.Compression()
+	if level < len(im.overviews.Compression) {
+		compression = im.overviews.Compression[level]
+	}
+	ov.SetCompression(compression)
+}
+
+// encodeOverviews builds and encodes the overviews of the image, whose samples are in buffer.
+// With SubIFDs, the overviews are the sub-images of the image from index first.
+func (im *Image) encodeOverviews(l *segmentLayout, buffer interface{}, first int) error {
+	opts := im.overviews
+	width, height := l.width, l.height
+	for level := 0; level < opts.levels(l); level++ {
+		buffer = downsample(buffer, width, height, l.samplesPerPixel, opts.Resampling)
+		width, height = (width+1)/2, (height+1)/2
+
+		var ov *Image
+		if opts.SubIFDs {
+			ov = im.SubImage[first+level]
+		} else {
+			ov = im.enc.NewImage()
+		}
+		im.newOverview(ov, level, width, height)
+		if err := ov.EncodeImage(buffer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downsample returns []uint8 or []uint16 samples with half the width and height of src, rounded up.
+func downsample(src interface{}, width, height, samplesPerPixel int, resampling Resampling) interface{} {
+	r := resampler{
+		width:           width,
+		height:          height,
+		samplesPerPixel: samplesPerPixel,
+	}
+	n := (width + 1) / 2 * ((height + 1) / 2) * samplesPerPixel
+	switch src := src.(type) {
+	case []uint8:
+		dst := make([]uint8, n)
+		r.get = func(i int) int { return int(src[i]) }
+		r.set = func(i int, v float64) { dst[i] = uint8(clampSample(v, math.MaxUint8)) }
+		r.resample(resampling)
+		return dst
+	case []uint16:
+		dst := make([]uint16, n)
+		r.get = func(i int) int { return int(src[i]) }
+		r.set = func(i int, v float64) { dst[i] = uint16(clampSample(v, math.MaxUint16)) }
+		r.resample(resampling)
+		return dst
+	default:
+		return nil
+	}
+}
+
+// clampSample rounds v to the nearest sample value between 0 and max.
+func clampSample(v float64, max float64) float64 {
+	v = math.Round(v)
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// resampler halves the width and height of interleaved samples.
+type resampler struct {
+	width           int // Width of the source
+	height          int // Height of the source
+	samplesPerPixel int
+
+	get func(i int) int        // Returns the i-th source sample
+	set func(i int, v float64) // Sets the i-th destination sample
+}
+
+func (r *resampler) resample(resampling Resampling) {
+	switch resampling {
+	case ResamplingAverage:
+		r.average()
+	case ResamplingMode:
+		r.mode()
+	case ResamplingLanczos:
+		r.lanczos()
+	default:
+		r.nearest()
+	}
+}
+
+func (r *resampler) nearest() {
+	spp := r.samplesPerPixel
+	dstWidth, dstHeight := (r.width+1)/2, (r.height+1)/2
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			src := (2*y*r.width + 2*x) * spp
+			dst := (y*dstWidth + x) * spp
+			for s := 0; s < spp; s++ {
+				r.set(dst+s, float64(r.get(src+s)))
+			}
+		}
+	}
+}
+
+// block returns the indices of the first sample of source pixels in the 2×2 block of (x, y),
+// clipped to the source.
+func (r *resampler) block(x, y int, indices []int) []int {
+	indices = indices[:0]
+	for sy := 2 * y; sy < 2*y+2 && sy < r.height; sy++ {
+		for sx := 2 * x; sx < 2*x+2 && sx < r.width; sx++ {
+			indices = append(indices, (sy*r.width+sx)*r.samplesPerPixel)
+		}
+	}
+	return indices
+}
+
+func (r *resampler) average() {
+	spp := r.samplesPerPixel
+	dstWidth, dstHeight := (r.width+1)/2, (r.height+1)/2
+	indices := make([]int, 0, 4)
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			indices = r.block(x, y, indices)
+			dst := (y*dstWidth + x) * spp
+			for s := 0; s < spp; s++ {
+				sum := 0
+				for _, i := range indices {
+					sum += r.get(i + s)
+				}
+				r.set(dst+s, float64(sum)/float64(len(indices)))
+			}
+		}
+	}
+}
+
+func (r *resampler) mode() {
+	spp := r.samplesPerPixel
+	dstWidth, dstHeight := (r.width+1)/2, (r.height+1)/2
+	indices := make([]int, 0, 4)
+	samePixel := func(i, j int) bool {
+		for s := 0; s < spp; s++ {
+			if r.get(i+s) != r.get(j+s) {
+				return false
+			}
+		}
+		return true
+	}
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			indices = r.block(x, y, indices)
+			// Ties are resolved in favor of the first pixel in row-major order
+			best, bestCount := indices[0], 0
+			for _, i := range indices {
+				count := 0
+				for _, j := range indices {
+					if samePixel(i, j) {
+						count++
+					}
+				}
+				if count > bestCount {
+					best, bestCount = i, count
+				}
+			}
+			dst := (y*dstWidth + x) * spp
+			for s := 0; s < spp; s++ {
+				r.set(dst+s, float64(r.get(best+s)))
+			}
+		}
+	}
+}
+
+// lanczosWeights returns, for each destination position along an axis of n source pixels,
+// the first source position and the normalized weights of the Lanczos filter scaled by 2.
+func lanczosWeights(n int) (first []int, weights [][]float64) {
+	const lobes = 3
+	sinc := func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		return math.Sin(math.Pi*x) / (math.Pi * x)
+	}
+	dstN := (n + 1) / 2
+	first = make([]int, dstN)
+	weights = make([][]float64, dstN)
+	for d := 0; d < dstN; d++ {
+		center := 2*float64(d) + 0.5
+		first[d] = int(math.Ceil(center - 2*lobes))
+		last := int(math.Floor(center + 2*lobes))
+		var sum float64
+		for i := first[d]; i <= last; i++ {
+			t := (float64(i) - center) / 2
+			w := 0.0
+			if math.Abs(t) < lobes {
+				w = sinc(t) * sinc(t/lobes)
+			}
+			weights[d] = append(weights[d], w)
+			sum += w
+		}
+		for i := range weights[d] {
+			weights[d][i] /= sum
+		}
+	}
+	return first, weights
+}
+
+func (r *resampler) lanczos() {
+	spp := r.samplesPerPixel
+	dstWidth, dstHeight := (r.width+1)/2, (r.height+1)/2
+	clamp := func(i, n int) int {
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	}
+
+	// Horizontal pass
+	firstX, weightsX := lanczosWeights(r.width)
+	tmp := make([]float64, dstWidth*r.height*spp)
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < dstWidth; x++ {
+			for k, w := range weightsX[x] {
+				src := (y*r.width + clamp(firstX[x]+k, r.width)) * spp
+				dst := (y*dstWidth + x) * spp
+				for s := 0; s < spp; s++ {
+					tmp[dst+s] += w * float64(r.get(src+s))
+				}
+			}
+		}
+	}
+
+	// Vertical pass
+	firstY, weightsY := lanczosWeights(r.height)
+	sum := make([]float64, spp)
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			for s := range sum {
+				sum[s] = 0
+			}
+			for k, w := range weightsY[y] {
+				src := (clamp(firstY[y]+k, r.height)*dstWidth + x) * spp
+				for s := 0; s < spp; s++ {
+					sum[s] += w * tmp[src+s]
+				}
+			}
+			dst := (y*dstWidth + x) * spp
+			for s := 0; s < spp; s++ {
+				r.set(dst+s, sum[s])
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package tiff_test
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestEncode_OverviewResampling(t *testing.T) {
+	src := []uint8{
+		10, 20, 30, 40, 7,
+		50, 60, 70, 81, 7,
+		10, 10, 7, 7, 7,
+	}
+	tests := []struct {
+		resampling tiff.Resampling
+		want       []uint8
+	}{
+		{tiff.ResamplingNearest, []uint8{10, 30, 7, 10, 7, 7}},
+		{tiff.ResamplingAverage, []uint8{35, 55, 7, 10, 7, 7}},
+		{tiff.ResamplingMode, []uint8{10, 30, 7, 10, 7, 7}},
+	}
+	for _, test := range tests {
+		w := &writeSeeker{}
+		enc := tiff.NewEncoder(w)
+		im := enc.NewImage()
+		im.SetWidthHeight(5, 3)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		im.SetOverviews(&tiff.OverviewOptions{Levels: 1, Resampling: test.resampling})
+		if err := im.EncodeImage(src); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ims, err := d.Iter().All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ims) != 2 {
+			t.Fatalf("found %d images, want 2", len(ims))
+		}
+		buf := make([]uint8, 3*2)
+		if err := ims[1].DecodeImage(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf, test.want) {
+			t.Errorf("resampling %d: overview %v, want %v", test.resampling, buf, test.want)
+		}
+	}
+}
+
+func TestEncode_Overviews(t *testing.T) {
+	const width, height = 100, 60
+	data := make([]uint16, width*height*3)
+	for i := range data {
+		data[i] = 1000
+	}
+	compressions := []int{tiff.CompressionDeflate, tiff.CompressionZstd}
+
+	for _, subIFDs := range []bool{false, true} {
+		for _, at := range []bool{false, true} {
+			var enc *tiff.Encoder
+			ws, wa := &writeSeeker{}, &writerAt{}
+			if at {
+				enc = tiff.NewEncoderAt(wa)
+			} else {
+				enc = tiff.NewEncoder(ws)
+			}
+			im := enc.NewImage()
+			im.SetWidthHeight(width, height)
+			im.SetPixelFormat(tiff.PhotometricRGB, 3, []int{16, 16, 16})
+			im.SetTileWidthHeight(16, 16)
+			im.SetOverviews(&tiff.OverviewOptions{
+				Resampling:  tiff.ResamplingLanczos,
+				Compression: compressions,
+				SubIFDs:     subIFDs,
+			})
+			if err := im.EncodeImage(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			file := ws.buf
+			if at {
+				file = wa.buf
+			}
+
+			d, err := tiff.NewDecoder(bytes.NewReader(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ims, err := d.Iter().All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			overviews := ims[1:]
+			if subIFDs {
+				if len(ims) != 1 {
+					t.Fatalf("found %d images, want 1", len(ims))
+				}
+				overviews = ims[0].SubImage
+			}
+			// 100×60 → 50×30 → 25×15 → 13×8, which fits in a tile
+			if len(overviews) != 3 {
+				t.Fatalf("SubIFDs = %v: found %d overviews, want 3", subIFDs, len(overviews))
+			}
+			w, h := width, height
+			for level, ov := range overviews {
+				w, h = (w+1)/2, (h+1)/2
+				if ovw, ovh := ov.WidthHeight(); ovw != w || ovh != h {
+					t.Errorf("overview %d is %d×%d, want %d×%d", level, ovw, ovh, w, h)
+				}
+				if v, _ := ov.Tag[tiff.TagNewSubfileType].Uint(); v != tiff.NewFiletypeReducedImage {
+					t.Errorf("overview %d: NewSubfileType = %d", level, v)
+				}
+				compression := tiff.CompressionNone
+				if level < len(compressions) {
+					compression = compressions[level]
+				}
+				if ov.Compression() != compression {
+					t.Errorf("overview %d: compression %d, want %d", level, ov.Compression(), compression)
+				}
+				buf := make([]uint16, w*h*3)
+				if err := ov.DecodeImage(buf); err != nil {
+					t.Fatal(err)
+				}
+				for i := range buf {
+					if buf[i] != 1000 {
+						t.Fatalf("overview %d: sample %d is %d, want 1000", level, i, buf[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDecoder_Pyramids(t *testing.T) {
+	const width, height = 100, 60
+	data := make([]uint8, width*height)
+	for i := range data {
+		data[i] = uint8(i % 251)
+	}
+
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	// Overviews as IFDs, overviews as SubIFDs, and no overview
+	for _, opts := range []*tiff.OverviewOptions{{}, {SubIFDs: true}, nil} {
+		im := enc.NewImage()
+		im.SetWidthHeight(width, height)
+		im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+		im.SetTileWidthHeight(16, 16)
+		if opts != nil {
+			im.SetOverviews(opts)
+		}
+		if err := im.EncodeImage(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pyramids, err := d.Pyramids()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pyramids) != 3 {
+		t.Fatalf("found %d pyramids, want 3", len(pyramids))
+	}
+	for i, want := range []int{4, 4, 1} {
+		if len(pyramids[i].Levels) != want {
+			t.Errorf("pyramid %d has %d levels, want %d", i, len(pyramids[i].Levels), want)
+		}
+	}
+
+	p := pyramids[1]
+	if x, y := p.Scale(2); x != 4 || y != 4 {
+		t.Errorf("Scale(2) = %v, %v, want 4, 4", x, y)
+	}
+	if level := p.BestLevel(30, 20); level != 1 {
+		t.Errorf("BestLevel(30, 20) = %d, want 1", level)
+	}
+	if level := p.BestLevel(200, 200); level != 0 {
+		t.Errorf("BestLevel(200, 200) = %d, want 0", level)
+	}
+
+	rect := image.Rect(20, 10, 60, 50)
+	level, r, buffer, err := p.DecodeRegion(rect, 10, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(5, 2, 15, 13); level != 2 || r != want {
+		t.Fatalf("DecodeRegion read %v of level %d, want %v of level 2", r, level, want)
+	}
+	want := make([]uint8, r.Dx()*r.Dy())
+	if err := p.Levels[2].DecodeRegion(r, want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(buffer, want) {
+		t.Error("DecodeRegion: data inconsistent with level")
+	}
+}
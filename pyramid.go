@@ -0,0 +1,124 @@
+package tiff
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Pyramid is a full resolution image with its overviews.
+type Pyramid struct {
+	// Levels are the full resolution image followed by its overviews, from the largest to the smallest.
+	Levels []*Image
+}
+
+// isReduced reports whether NewSubfileType marks the image as a reduced resolution version of another image.
+func (im *Image) isReduced() bool {
+	v, _ := im.Tag[TagNewSubfileType].Uint()
+	return v&NewFiletypeReducedImage != 0
+}
+
+// Pyramids returns the images of the file grouped with their overviews.
+//
+// Overviews are the images following a full resolution image with NewSubfileType of NewFiletypeReducedImage,
+// and the SubIFDs of the full resolution image with the same NewSubfileType. Other SubIFDs, such as thumbnails
+// which are not marked as reduced images, are not included.
+func (d *Decoder) Pyramids() ([]*Pyramid, error) {
+	var pyramids []*Pyramid
+	it := d.Iter()
+	for it.Next() {
+		im := it.Image()
+		if !im.isReduced() || len(pyramids) == 0 {
+			pyramids = append(pyramids, &Pyramid{Levels: []*Image{im}})
+		} else {
+			p := pyramids[len(pyramids)-1]
+			p.Levels = append(p.Levels, im)
+		}
+		for _, sub := range im.SubImage {
+			if sub.isReduced() {
+				p := pyramids[len(pyramids)-1]
+				p.Levels = append(p.Levels, sub)
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range pyramids {
+		sort.SliceStable(p.Levels[1:], func(i, j int) bool {
+			wi, hi := p.Levels[1+i].WidthHeight()
+			wj, hj := p.Levels[1+j].WidthHeight()
+			return wi*hi > wj*hj
+		})
+	}
+	return pyramids, nil
+}
+
+// Scale returns the ratios between the width and height of the full resolution image and the level.
+func (p *Pyramid) Scale(level int) (x float64, y float64) {
+	width, height := p.Levels[0].WidthHeight()
+	levelWidth, levelHeight := p.Levels[level].WidthHeight()
+	return float64(width) / float64(levelWidth), float64(height) / float64(levelHeight)
+}
+
+// LevelRegion returns the region of the level covering rect of the full resolution image.
+func (p *Pyramid) LevelRegion(level int, rect image.Rectangle) image.Rectangle {
+	x, y := p.Scale(level)
+	r := image.Rect(
+		int(math.Floor(float64(rect.Min.X)/x)),
+		int(math.Floor(float64(rect.Min.Y)/y)),
+		int(math.Ceil(float64(rect.Max.X)/x)),
+		int(math.Ceil(float64(rect.Max.Y)/y)),
+	)
+	width, height := p.Levels[level].WidthHeight()
+	return r.Intersect(image.Rect(0, 0, width, height))
+}
+
+// BestLevel returns the smallest level with at least width×height pixels,
+// or 0 if the full resolution image is smaller.
+func (p *Pyramid) BestLevel(width, height int) int {
+	fullWidth, fullHeight := p.Levels[0].WidthHeight()
+	return p.bestLevel(image.Rect(0, 0, fullWidth, fullHeight), width, height)
+}
+
+// bestLevel returns the smallest level with at least width×height pixels within rect of the full resolution image.
+func (p *Pyramid) bestLevel(rect image.Rectangle, width, height int) int {
+	best := 0
+	for level := 1; level < len(p.Levels); level++ {
+		x, y := p.Scale(level)
+		if float64(rect.Dx())/x < float64(width) || float64(rect.Dy())/y < float64(height) {
+			break
+		}
+		best = level
+	}
+	return best
+}
+
+// DecodeRegion decodes rect of the full resolution image, to be displayed as width×height pixels,
+// from the smallest level with at least width×height pixels within rect.
+//
+// It returns the level, the region of the level which is decoded, and its samples in a new buffer
+// of the type required by DecodeImage.
+func (p *Pyramid) DecodeRegion(rect image.Rectangle, width, height int, opts *DecodeOptions) (level int, r image.Rectangle, buffer interface{}, err error) {
+	level = p.bestLevel(rect, width, height)
+	r = p.LevelRegion(level, rect)
+	if r.Empty() {
+		return 0, image.Rectangle{}, nil, fmt.Errorf("region %v is out of image bounds", rect)
+	}
+	im := p.Levels[level]
+	n := r.Dx() * r.Dy() * im.SamplesPerPixel()
+	switch im.DataType() {
+	case Uint8:
+		buffer = make([]uint8, n)
+	case Uint16:
+		buffer = make([]uint16, n)
+	default:
+		return 0, image.Rectangle{}, nil, UnsupportedError(fmt.Sprintf("BitsPerSample of %v", im.BitsPerSample()))
+	}
+	err = im.DecodeRegionWithOptions(r, buffer, opts)
+	if err != nil {
+		return 0, image.Rectangle{}, nil, err
+	}
+	return level, r, buffer, nil
+}
@@ -0,0 +1,313 @@
+package tiff
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+)
+
+// segmentLayout describes how image data are divided into strips or tiles.
+type segmentLayout struct {
+	width           int // Width of the image
+	height          int // Height of the image
+	samplesPerPixel int
+	bytesPerSample  int
+	predictor       int
+
+	tiled         bool
+	segmentWidth  int // Width of a tile, or width of the image for strips
+	segmentHeight int // Height of a tile, or RowsPerStrip for strips
+	nx            int // Number of segments per row
+	ny            int // Number of segments per column
+
+	offsets    []uint // Offsets of segments, decoded once for all segments
+	byteCounts []uint // Byte counts of segments, decoded once for all segments
+}
+
+// segmentLayout validates the size, pixel format and segmentation of the image data.
+func (im *Image) segmentLayout() (*segmentLayout, error) {
+	//
+	// Find width and height
+	//
+	width, height := im.WidthHeight()
+	if width == 0 {
+		return nil, fmt.Errorf("invalid image width")
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("invalid image height")
+	}
+	l := &segmentLayout{
+		width:           width,
+		height:          height,
+		samplesPerPixel: im.SamplesPerPixel(),
+	}
+
+	//
+	// Find bitDepth
+	//
+	bitDepth := im.BitDepth()
+	switch bitDepth {
+	case 1:
+		return nil, UnsupportedError("1-bit image is not yet supported")
+	case 8, 16:
+		l.bytesPerSample = bitDepth / 8
+	default:
+		bitsPerSample := im.BitsPerSample()
+		return nil, UnsupportedError(fmt.Sprintf("BitsPerSample of %v", bitsPerSample))
+	}
+
+	//
+	// PlanarConfig
+	//
+	planarConfig := im.PlanarConfig()
+	if planarConfig != PlanarConfigContig {
+		return nil, UnsupportedError(fmt.Sprintf("PlanarConfiguration of %v", planarConfig))
+	}
+
+	//
+	// Predictor
+	//
+	l.predictor = im.Predictor()
+	if l.predictor != PredictorNone && l.predictor != PredictorHorizontal {
+		return nil, UnsupportedError(fmt.Sprintf("Predictor of %v", l.predictor))
+	}
+
+	//
+	// Strip mode
+	//
+	numStrips := im.NumStrips()
+	if numStrips > 0 {
+		// Find expected number of strips
+		rowsPerStrip, ok := im.Tag[TagRowsPerStrip].Uint()
+		if im.Tag[TagRowsPerStrip] == nil {
+			// Default = 2**32-1, i.e. the whole image is one strip. (TIFF 6.0 Page 39)
+			rowsPerStrip, ok = uint(height), true
+		}
+		if !ok || rowsPerStrip == 0 {
+			return nil, FormatError("invalid RowsPerStrip")
+		}
+		if rowsPerStrip > uint(height) {
+			rowsPerStrip = uint(height)
+		}
+		l.segmentWidth = width
+		l.segmentHeight = int(rowsPerStrip)
+		l.nx = 1
+		l.ny = (height + l.segmentHeight - 1) / l.segmentHeight
+		if l.ny != numStrips {
+			return nil, FormatError(fmt.Sprintf("expecting %d strips, found %d strips", l.ny, numStrips))
+		}
+		if err := im.decodeSegmentTable(l); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	//
+	// Tile mode
+	//
+	numTiles := im.NumTiles()
+	if numTiles > 0 {
+		// Find expected number of tiles
+		tileHeight, ok := im.Tag[TagTileLength].Uint()
+		if !ok || tileHeight == 0 {
+			return nil, FormatError("invalid TagTileLength")
+		}
+		tileWidth, ok := im.Tag[TagTileWidth].Uint()
+		if !ok || tileWidth == 0 {
+			return nil, FormatError("invalid TagTileWidth")
+		}
+		l.tiled = true
+		l.segmentWidth = int(tileWidth)
+		l.segmentHeight = int(tileHeight)
+		l.nx = (width + l.segmentWidth - 1) / l.segmentWidth
+		l.ny = (height + l.segmentHeight - 1) / l.segmentHeight
+		if l.nx*l.ny != numTiles {
+			return nil, FormatError(fmt.Sprintf("expecting %d tiles, found %d tiles", l.nx*l.ny, numTiles))
+		}
+		if err := im.decodeSegmentTable(l); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("no strip or tile found")
+}
+
+// decodeLayout returns the segment layout of a decoded image. It is built once and shared
+// by DecodeRegion, DecodeTile and DecodeStrip, so that the offsets and byte counts of segments
+// are not decoded again for each call. SetTag discards it.
+func (im *Image) decodeLayout() (*segmentLayout, error) {
+	im.layoutMu.Lock()
+	defer im.layoutMu.Unlock()
+	if im.layout == nil {
+		l, err := im.segmentLayout()
+		if err != nil {
+			return nil, err
+		}
+		im.layout = l
+	}
+	return im.layout, nil
+}
+
+// decodeSegmentTable decodes the offsets and byte counts of all segments.
+func (im *Image) decodeSegmentTable(l *segmentLayout) error {
+	offsetTagID, byteCountTagID := l.segmentTagIDs()
+	var ok bool
+	l.offsets, ok = im.Tag[offsetTagID].UintSlice()
+	if !ok {
+		return FormatError(fmt.Sprintf("invalid %v", offsetTagID))
+	}
+	l.byteCounts, ok = im.Tag[byteCountTagID].UintSlice()
+	if !ok {
+		return FormatError(fmt.Sprintf("invalid %v", byteCountTagID))
+	}
+	return nil
+}
+
+// isSparse reports whether the segment at given index has zero offset and byte count.
+func (l *segmentLayout) isSparse(index int) bool {
+	return l.offsets[index] == 0 && l.byteCounts[index] == 0
+}
+
+// rawSegmentReader returns a Reader for reading the raw data of the segment at given index.
+func (l *segmentLayout) rawSegmentReader(r io.ReaderAt, index int) *io.SectionReader {
+	return io.NewSectionReader(r, int64(l.offsets[index]), int64(l.byteCounts[index]))
+}
+
+// bounds returns the bounds of the image.
+func (l *segmentLayout) bounds() image.Rectangle {
+	return image.Rect(0, 0, l.width, l.height)
+}
+
+// segmentBounds returns the part of the image covered by the segment at column ix and row iy,
+// excluding the padding of tiles.
+func (l *segmentLayout) segmentBounds(ix, iy int) image.Rectangle {
+	r := image.Rect(ix*l.segmentWidth, iy*l.segmentHeight, (ix+1)*l.segmentWidth, (iy+1)*l.segmentHeight)
+	return r.Intersect(l.bounds())
+}
+
+// segmentBytes returns the size of the decompressed data of a full segment.
+func (l *segmentLayout) segmentBytes() int {
+	return l.segmentWidth * l.segmentHeight * l.samplesPerPixel * l.bytesPerSample
+}
+
+func (l *segmentLayout) segmentName() string {
+	if l.tiled {
+		return "tile"
+	}
+	return "strip"
+}
+
+// checkBuffer validates the type and size of a buffer holding pixelCount pixels.
+func (l *segmentLayout) checkBuffer(buffer interface{}, pixelCount int) error {
+	switch l.bytesPerSample {
+	case 1:
+		if buf, ok := buffer.([]uint8); ok {
+			if len(buf) != pixelCount*l.samplesPerPixel {
+				return fmt.Errorf("wrong buffer size")
+			}
+		} else {
+			return fmt.Errorf("expecting []uint8")
+		}
+	case 2:
+		if buf, ok := buffer.([]uint16); ok {
+			if len(buf) != pixelCount*l.samplesPerPixel {
+				return fmt.Errorf("wrong buffer size")
+			}
+		} else {
+			return fmt.Errorf("expecting []uint16")
+		}
+	}
+	return nil
+}
+
+// sliceBuffer validates the type of a buffer, and returns its first pixelCount pixels.
+func (l *segmentLayout) sliceBuffer(buffer interface{}, pixelCount int) (interface{}, error) {
+	n := pixelCount * l.samplesPerPixel
+	switch l.bytesPerSample {
+	case 1:
+		if buf, ok := buffer.([]uint8); ok {
+			if len(buf) < n {
+				return nil, fmt.Errorf("buffer too small")
+			}
+			return buf[:n], nil
+		}
+		return nil, fmt.Errorf("expecting []uint8")
+	default:
+		if buf, ok := buffer.([]uint16); ok {
+			if len(buf) < n {
+				return nil, fmt.Errorf("buffer too small")
+			}
+			return buf[:n], nil
+		}
+		return nil, fmt.Errorf("expecting []uint16")
+	}
+}
+
+// undoPredictor reverses the prediction scheme on decompressed data of a segment in place.
+func (l *segmentLayout) undoPredictor(data []byte, byteOrder binary.ByteOrder) {
+	if l.predictor != PredictorHorizontal {
+		return
+	}
+	spp := l.samplesPerPixel
+	samplesPerRow := l.segmentWidth * spp
+	switch l.bytesPerSample {
+	case 1:
+		for offset := 0; offset+samplesPerRow <= len(data); offset += samplesPerRow {
+			row := data[offset : offset+samplesPerRow]
+			for i := spp; i < samplesPerRow; i++ {
+				row[i] += row[i-spp]
+			}
+		}
+	case 2:
+		for offset := 0; offset+2*samplesPerRow <= len(data); offset += 2 * samplesPerRow {
+			row := data[offset : offset+2*samplesPerRow]
+			for i := spp; i < samplesPerRow; i++ {
+				v := byteOrder.Uint16(row[2*i:]) + byteOrder.Uint16(row[2*(i-spp):])
+				byteOrder.PutUint16(row[2*i:], v)
+			}
+		}
+	}
+}
+
+// copySegment copies decompressed data of a segment covering src to buffer covering dst.
+//
+// Rows of the segment data are segmentWidth pixels apart, while rows of buffer are dst.Dx() pixels apart.
+// Only the intersection of src and dst is copied.
+func (l *segmentLayout) copySegment(buffer interface{}, dst image.Rectangle, data []byte, src image.Rectangle, byteOrder binary.ByteOrder) {
+	r := dst.Intersect(src)
+	if r.Empty() {
+		return
+	}
+
+	spp := l.samplesPerPixel
+	count := r.Dx() * spp
+	srcStride := l.segmentWidth * spp
+	dstStride := dst.Dx() * spp
+	srcOffset := (r.Min.Y-src.Min.Y)*srcStride + (r.Min.X-src.Min.X)*spp
+	destOffset := (r.Min.Y-dst.Min.Y)*dstStride + (r.Min.X-dst.Min.X)*spp
+
+	// Copy data line-by-line
+	for iy := r.Min.Y; iy < r.Max.Y; iy++ {
+		switch buf := buffer.(type) {
+		case []uint8:
+			copy(buf[destOffset:destOffset+count], data[srcOffset:srcOffset+count])
+		case []uint16:
+			// Interpret data as uint16
+			src := data[2*srcOffset : 2*(srcOffset+count)]
+			dst := buf[destOffset : destOffset+count]
+			if byteOrder == binary.LittleEndian {
+				for i := range dst {
+					dst[i] = (uint16)(src[2*i]) | ((uint16)(src[2*i+1]) << 8)
+				}
+			} else {
+				for i := range dst {
+					dst[i] = ((uint16)(src[2*i]) << 8) | (uint16)(src[2*i+1])
+				}
+			}
+		}
+		srcOffset += srcStride
+		destOffset += dstStride
+	}
+}
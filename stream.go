@@ -0,0 +1,256 @@
+package tiff
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RowWriter encodes image data row by row, for images that are too large to be held in memory.
+//
+// Only one strip, or one row of tiles, is buffered at a time. Once it is complete,
+// it is compressed and written to the file.
+type RowWriter struct {
+	im *Image
+	l  *segmentLayout
+
+	band       []byte // Data of the current strip or row of tiles
+	bandRows   int    // Number of rows in band
+	iy         int    // Index of the current strip or row of tiles
+	rows       int    // Number of rows written
+	offsets    []uint64
+	byteCounts []uint64
+	err        error
+}
+
+// NewRowWriter writes the IFD of the image and returns a RowWriter for its image data.
+//
+// The width, height and pixel format of the image need to be set before calling NewRowWriter,
+// and the image is complete after all rows are written and the RowWriter is closed.
+func (im *Image) NewRowWriter() (*RowWriter, error) {
+	if im.overviews != nil {
+		return nil, fmt.Errorf("RowWriter does not support overviews")
+	}
+	l, err := im.newSegmentLayout()
+	if err != nil {
+		return nil, err
+	}
+	err = im.beginEncode(l)
+	if err != nil {
+		return nil, err
+	}
+	numSegments := l.nx * l.ny
+	bytesPerRow := l.width * l.samplesPerPixel * l.bytesPerSample
+	return &RowWriter{
+		im:         im,
+		l:          l,
+		band:       make([]byte, 0, l.segmentHeight*bytesPerRow),
+		offsets:    make([]uint64, numSegments),
+		byteCounts: make([]uint64, numSegments),
+	}, nil
+}
+
+// WriteRows writes one or more rows of image data.
+//
+// The rows are []uint8 or []uint16 as required by EncodeImage, and hold a multiple of width*SamplesPerPixel() samples.
+func (w *RowWriter) WriteRows(rows interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	samplesPerRow := w.l.width * w.l.samplesPerPixel
+	var numSamples int
+	switch buf := rows.(type) {
+	case []uint8:
+		numSamples = len(buf)
+	case []uint16:
+		numSamples = len(buf)
+	default:
+		return fmt.Errorf("unsupported buffer type %T", rows)
+	}
+	if numSamples%samplesPerRow != 0 {
+		return fmt.Errorf("rows are not a multiple of %d samples", samplesPerRow)
+	}
+	numRows := numSamples / samplesPerRow
+	if numRows == 0 {
+		return nil
+	}
+	if w.rows+numRows > w.l.height {
+		return fmt.Errorf("too many rows: image height is %d", w.l.height)
+	}
+	if err := w.l.checkBuffer(rows, numRows*w.l.width); err != nil {
+		return err
+	}
+
+	// Rows are converted to the byte order of the file as they are copied to the band
+	bytesPerRow := samplesPerRow * w.l.bytesPerSample
+	for offset := 0; offset < numRows*bytesPerRow; {
+		// Number of rows to complete the current band, which can be shorter at the bottom of the image
+		n := w.l.segmentHeight - w.bandRows
+		if remaining := w.l.height - w.iy*w.l.segmentHeight - w.bandRows; remaining < n {
+			n = remaining
+		}
+		if left := numRows - offset/bytesPerRow; n > left {
+			n = left
+		}
+		start := len(w.band)
+		w.band = w.band[:start+n*bytesPerRow]
+		copySamples(w.band[start:], rows, offset, w.im.Header.ByteOrder)
+		offset += n * bytesPerRow
+		w.bandRows += n
+		w.rows += n
+
+		if w.bandRows == w.l.segmentHeight || w.rows == w.l.height {
+			if err := w.flush(); err != nil {
+				w.err = err
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush compresses and writes the current band.
+func (w *RowWriter) flush() error {
+	band := *w.l
+	band.height = w.bandRows
+	band.ny = 1
+	err := w.im.writeSegments(&band, w.band, w.iy*w.l.nx, func(index int, offset int64, byteCount int) {
+		w.offsets[index] = uint64(offset)
+		w.byteCounts[index] = uint64(byteCount)
+	})
+	if err != nil {
+		return err
+	}
+	w.band = w.band[:0]
+	w.bandRows = 0
+	w.iy++
+	return nil
+}
+
+// Close completes the image by writing the offsets and byte counts of segments to its IFD.
+//
+// It returns an error if fewer rows than the image height have been written.
+func (w *RowWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.rows != w.l.height {
+		return fmt.Errorf("incomplete image: %d of %d rows written", w.rows, w.l.height)
+	}
+	w.err = fmt.Errorf("RowWriter is closed")
+	return w.im.finishEncode(w.l, w.offsets, w.byteCounts)
+}
+
+// TileWriter encodes the tiles of an image in any order.
+//
+// WriteTile is safe for concurrent use. Tiles are compressed by the calling goroutine,
+// and written to the file as they arrive. With an Encoder from NewEncoderAt, tiles are also written concurrently,
+// and TileWriters of several images can be used at the same time. Otherwise, other images of the Encoder must not
+// be encoded until the TileWriter is closed.
+type TileWriter struct {
+	im *Image
+	l  *segmentLayout
+
+	mu         sync.Mutex
+	written    []bool
+	offsets    []uint64
+	byteCounts []uint64
+	closed     bool
+}
+
+// NewTileWriter writes the IFD of a tiled image and returns a TileWriter for its tiles.
+//
+// The width, height, pixel format and tile size of the image need to be set before calling NewTileWriter,
+// and the image is complete after the TileWriter is closed.
+func (im *Image) NewTileWriter() (*TileWriter, error) {
+	if im.overviews != nil {
+		return nil, fmt.Errorf("TileWriter does not support overviews")
+	}
+	l, err := im.newSegmentLayout()
+	if err != nil {
+		return nil, err
+	}
+	if !l.tiled {
+		return nil, fmt.Errorf("image is not organized in tiles")
+	}
+	err = im.beginEncode(l)
+	if err != nil {
+		return nil, err
+	}
+	numSegments := l.nx * l.ny
+	return &TileWriter{
+		im:         im,
+		l:          l,
+		written:    make([]bool, numSegments),
+		offsets:    make([]uint64, numSegments),
+		byteCounts: make([]uint64, numSegments),
+	}, nil
+}
+
+// WriteTile compresses and writes the tile at column ix and row iy.
+//
+// The data are []uint8 or []uint16 as required by EncodeImage, and hold either a full tile
+// of TileWidth*TileLength*SamplesPerPixel() samples, or only the part of the tile within the image
+// in row-major order, as returned by DecodeTile. Each tile can be written only once.
+func (w *TileWriter) WriteTile(ix, iy int, data interface{}) error {
+	l := w.l
+	if ix < 0 || ix >= l.nx || iy < 0 || iy >= l.ny {
+		return fmt.Errorf("tile (%d, %d) out of range", ix, iy)
+	}
+	rect := l.segmentBounds(ix, iy)
+
+	// Use the layout of a single tile to validate data and pad the tile
+	tile := *l
+	tile.width = rect.Dx()
+	tile.height = rect.Dy()
+	if tile.checkBuffer(data, l.segmentWidth*l.segmentHeight) == nil {
+		tile.width = l.segmentWidth
+		tile.height = l.segmentHeight
+	} else if err := tile.checkBuffer(data, rect.Dx()*rect.Dy()); err != nil {
+		return err
+	}
+	tile.nx = 1
+	tile.ny = 1
+	buf, err := w.im.compressSegment(tile.segmentData(data, w.im.Header.ByteOrder, 0, 0))
+	if err != nil {
+		return err
+	}
+
+	index := iy*l.nx + ix
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return fmt.Errorf("TileWriter is closed")
+	}
+	if w.written[index] {
+		w.mu.Unlock()
+		return fmt.Errorf("tile (%d, %d) is already written", ix, iy)
+	}
+	w.written[index] = true
+	w.mu.Unlock()
+
+	offset, err := w.im.writeSegment(index, buf)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.written[index] = false
+		return err
+	}
+	w.offsets[index] = uint64(offset)
+	w.byteCounts[index] = uint64(len(buf))
+	return nil
+}
+
+// Close completes the image by writing the offsets and byte counts of tiles to its IFD.
+//
+// Tiles that have not been written are recorded as sparse tiles, with zero offset and byte count.
+// Close must be called after all calls of WriteTile have returned.
+func (w *TileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return fmt.Errorf("TileWriter is closed")
+	}
+	w.closed = true
+	return w.im.finishEncode(w.l, w.offsets, w.byteCounts)
+}
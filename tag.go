@@ -234,8 +234,8 @@ func NewTag(id TagID, tagType TagType, value interface{}, header *Header) (tag *
 		tag.Count = len(vSlice)
 		tag.Data = make([]byte, tag.Count*8)
 		for i := 0; i < tag.Count; i++ {
-			header.ByteOrder.PutUint32(tag.Data[8*i:8*(i+1)], vSlice[i][0])
-			header.ByteOrder.PutUint32(tag.Data[8*(i+1):4*(i+2)], vSlice[i][1])
+			header.ByteOrder.PutUint32(tag.Data[8*i:8*i+4], vSlice[i][0])
+			header.ByteOrder.PutUint32(tag.Data[8*i+4:8*(i+1)], vSlice[i][1])
 		}
 	case []SRational:
 		if tagType != TagTypeSRational {
@@ -244,8 +244,8 @@ func NewTag(id TagID, tagType TagType, value interface{}, header *Header) (tag *
 		tag.Count = len(vSlice)
 		tag.Data = make([]byte, tag.Count*8)
 		for i := 0; i < tag.Count; i++ {
-			header.ByteOrder.PutUint32(tag.Data[8*i:8*(i+1)], uint32(vSlice[i][0]))
-			header.ByteOrder.PutUint32(tag.Data[8*(i+1):4*(i+2)], uint32(vSlice[i][1]))
+			header.ByteOrder.PutUint32(tag.Data[8*i:8*i+4], uint32(vSlice[i][0]))
+			header.ByteOrder.PutUint32(tag.Data[8*i+4:8*(i+1)], uint32(vSlice[i][1]))
 		}
 	default:
 		return nil, fmt.Errorf("invalid value type")
@@ -257,90 +257,6 @@ func (t *Tag) GoString() string {
 	return formatTagValue(t.Type, t.Count, t.Data, t.Header.ByteOrder)
 }
 
-func (t *Tag) String() (value string, ok bool) {
-	if t == nil {
-		return "", false
-	}
-	if t.Type != TagTypeASCII {
-		return "", false
-	}
-	v, err := DecodeASCII(t.Count, t.Data, t.Header.ByteOrder)
-	if err != nil {
-		return "", false
-	}
-	return v[0], true
-}
-
-func (t *Tag) StringSlice() (value []string, ok bool) {
-	if t == nil {
-		return nil, false
-	}
-	if t.Type != TagTypeASCII {
-		return nil, false
-	}
-	v, err := DecodeASCII(t.Count, t.Data, t.Header.ByteOrder)
-	if err != nil {
-		return nil, false
-	}
-	return v, true
-}
-
-// Uint decodes Short, Long or Long8 tag value as uint, when the tag only has one value.
-//
-// It returns (0, false) when the tags does not exist, has zero count or multiple values, or is of other data type.
-func (t *Tag) Uint() (value uint, ok bool) {
-	if t == nil || t.Count != 1 {
-		return 0, false
-	}
-	switch t.Type {
-	case TagTypeShort:
-		v := DecodeShort(t.Count, t.Data, t.Header.ByteOrder)
-		return uint(v[0]), true
-	case TagTypeLong, TagTypeIFD:
-		v := DecodeLong(t.Count, t.Data, t.Header.ByteOrder)
-		return uint(v[0]), true
-	case TagTypeLong8, TagTypeIFD8:
-		v := DecodeLong8(t.Count, t.Data, t.Header.ByteOrder)
-		return uint(v[0]), true
-	default:
-		return 0, false
-	}
-}
-
-// UintSlice decodes Short, Long or Long8 tag values as []uint.
-//
-// It returns ([]uint{}, false) when the tags does not exist, has zero-count, or is of other data type.
-func (t *Tag) UintSlice() (value []uint, ok bool) {
-	if t == nil || t.Count == 0 {
-		return []uint{}, false
-	}
-	switch t.Type {
-	case TagTypeShort:
-		v := DecodeShort(t.Count, t.Data, t.Header.ByteOrder)
-		value = make([]uint, len(v))
-		for i := 0; i < len(v); i++ {
-			value[i] = uint(v[i])
-		}
-		return value, true
-	case TagTypeLong, TagTypeIFD:
-		v := DecodeLong(t.Count, t.Data, t.Header.ByteOrder)
-		value = make([]uint, len(v))
-		for i := 0; i < len(v); i++ {
-			value[i] = uint(v[i])
-		}
-		return value, true
-	case TagTypeLong8, TagTypeIFD8:
-		v := DecodeLong8(t.Count, t.Data, t.Header.ByteOrder)
-		value = make([]uint, len(v))
-		for i := 0; i < len(v); i++ {
-			value[i] = uint(v[i])
-		}
-		return value, true
-	default:
-		return []uint{}, false
-	}
-}
-
 type ExifTag struct {
 	ID    ExifTagID // Tag identifying code
 	Type  TagType   // Data type of tag data
@@ -369,6 +285,10 @@ type GPSTag struct {
 	OffsetData  int64   // Offset of the data if the data are not in the IFD Entry
 }
 
+func (t *GPSTag) GoString() string {
+	return formatTagValue(t.Type, t.Count, t.Data, t.Header.ByteOrder)
+}
+
 type InteroperabilityTag struct {
 	ID    InteroperabilityTagID // Tag identifying code
 	Type  TagType               // Data type of tag data
@@ -381,6 +301,10 @@ type InteroperabilityTag struct {
 	OffsetData  int64   // Offset of the data if the data are not in the IFD Entry
 }
 
+func (t *InteroperabilityTag) GoString() string {
+	return formatTagValue(t.Type, t.Count, t.Data, t.Header.ByteOrder)
+}
+
 func DecodeASCII(count int, data []byte, byteOrder binary.ByteOrder) ([]string, error) {
 	if len(data) != count {
 		panic("decode tag: unexpected size of data")
@@ -106,6 +106,21 @@ const (
 
 	PlanarConfigContig   = 1 // The component values for each pixel are stored contiguously, e.g, RGBRGB...RGB
 	PlanarConfigSeparate = 2 // The components are stored in separate component planes, e.g., RR..RGG..GBB..B
+
+	PredictorNone          = 1 // No prediction scheme used before coding
+	PredictorHorizontal    = 2 // Horizontal differencing
+	PredictorFloatingPoint = 3 // Floating point horizontal differencing
+
+	SampleFormatUint          = 1 // Unsigned integer data
+	SampleFormatInt           = 2 // Two's complement signed integer data
+	SampleFormatIEEEFP        = 3 // IEEE floating point data
+	SampleFormatVoid          = 4 // Undefined data format
+	SampleFormatComplexInt    = 5 // Complex signed integer data
+	SampleFormatComplexIEEEFP = 6 // Complex IEEE floating point data
+
+	ExtraSamplesUnspecified       = 0 // Unspecified data
+	ExtraSamplesAssociatedAlpha   = 1 // Associated alpha data (with pre-multiplied color)
+	ExtraSamplesUnassociatedAlpha = 2 // Unassociated alpha data
 )
 
 const (
@@ -205,6 +220,8 @@ const (
 	TagXMP                    TagID = 700   // Extended. XML packet containing XMP metadata
 	TagImageID                TagID = 32781 // Supplement1. OPI-related.
 	TagCopyright              TagID = 33432 // Baseline. Copyright notice.
+	TagIPTC                   TagID = 33723 // Extended. IPTC-IIM records, such as the caption and keywords of news photos.
+	TagPhotoshop              TagID = 34377 // Extended. Photoshop image resources, which may contain IPTC-IIM records.
 	TagImageLayer             TagID = 34732 // Extended. Defined in the Mixed Raster Content part of RFC 2301, used to denote the particular function of this Image in the mixed raster scheme.
 
 	// From libtiff
@@ -230,6 +247,18 @@ const (
 	TagGPSIFD              TagID = 34853
 	TagInteroperabilityIFD TagID = 40965
 
+	// GeoTIFF
+	TagModelPixelScale     TagID = 33550 // Double. Size of raster pixels in model space units.
+	TagModelTiepoint       TagID = 33922 // Double. Tie points between raster space and model space.
+	TagModelTransformation TagID = 34264 // Double. Affine transformation matrix from raster space to model space.
+	TagGeoKeyDirectory     TagID = 34735 // Short. Directory of GeoKeys.
+	TagGeoDoubleParams     TagID = 34736 // Double. Values of GeoKeys referenced by the GeoKeyDirectory.
+	TagGeoASCIIParams      TagID = 34737 // ASCII. Values of GeoKeys referenced by the GeoKeyDirectory.
+
+	// GDAL
+	TagGDALMetadata TagID = 42112 // ASCII. XML document of GDAL metadata items.
+	TagGDALNoData   TagID = 42113 // ASCII. The value of samples marking missing data.
+
 	// EXIF Tags
 	ExifTagExposureTime             ExifTagID = 33434
 	ExifTagFNumber                  ExifTagID = 33437
@@ -242,6 +271,9 @@ const (
 	ExifTagExifVersion              ExifTagID = 36864
 	ExifTagDateTimeOriginal         ExifTagID = 36867
 	ExifTagDateTimeDigitized        ExifTagID = 36868
+	ExifTagOffsetTime               ExifTagID = 36880
+	ExifTagOffsetTimeOriginal       ExifTagID = 36881
+	ExifTagOffsetTimeDigitized      ExifTagID = 36882
 	ExifTagComponentsConfiguration  ExifTagID = 37121
 	ExifTagCompressedBitsPerPixel   ExifTagID = 37122
 	ExifTagShutterSpeedValue        ExifTagID = 37377
@@ -296,6 +328,40 @@ const (
 	ExifTagLensModel                ExifTagID = 42036
 	ExifTagLensSerialNumber         ExifTagID = 42037
 
+	// GPS Tags
+	GPSTagVersionID         GPSTagID = 0  // Byte. Version of the GPS IFD, e.g. 2.3.0.0
+	GPSTagLatitudeRef       GPSTagID = 1  // ASCII. 'N' or 'S'
+	GPSTagLatitude          GPSTagID = 2  // Rational. Degrees, minutes and seconds
+	GPSTagLongitudeRef      GPSTagID = 3  // ASCII. 'E' or 'W'
+	GPSTagLongitude         GPSTagID = 4  // Rational. Degrees, minutes and seconds
+	GPSTagAltitudeRef       GPSTagID = 5  // Byte. 0 above sea level, 1 below sea level
+	GPSTagAltitude          GPSTagID = 6  // Rational. Meters
+	GPSTagTimeStamp         GPSTagID = 7  // Rational. UTC hours, minutes and seconds
+	GPSTagSatellites        GPSTagID = 8  // ASCII
+	GPSTagStatus            GPSTagID = 9  // ASCII. 'A' measurement in progress, 'V' measurement interrupted
+	GPSTagMeasureMode       GPSTagID = 10 // ASCII. '2' or '3' dimensional
+	GPSTagDOP               GPSTagID = 11 // Rational. Dilution of precision
+	GPSTagSpeedRef          GPSTagID = 12 // ASCII. 'K' km/h, 'M' mph, 'N' knots
+	GPSTagSpeed             GPSTagID = 13 // Rational
+	GPSTagTrackRef          GPSTagID = 14 // ASCII. 'T' true direction, 'M' magnetic direction
+	GPSTagTrack             GPSTagID = 15 // Rational. Degrees
+	GPSTagImgDirectionRef   GPSTagID = 16 // ASCII. 'T' true direction, 'M' magnetic direction
+	GPSTagImgDirection      GPSTagID = 17 // Rational. Degrees
+	GPSTagMapDatum          GPSTagID = 18 // ASCII. Geodetic datum, e.g. WGS-84
+	GPSTagDestLatitudeRef   GPSTagID = 19 // ASCII. 'N' or 'S'
+	GPSTagDestLatitude      GPSTagID = 20 // Rational. Degrees, minutes and seconds
+	GPSTagDestLongitudeRef  GPSTagID = 21 // ASCII. 'E' or 'W'
+	GPSTagDestLongitude     GPSTagID = 22 // Rational. Degrees, minutes and seconds
+	GPSTagDestBearingRef    GPSTagID = 23 // ASCII. 'T' true direction, 'M' magnetic direction
+	GPSTagDestBearing       GPSTagID = 24 // Rational. Degrees
+	GPSTagDestDistanceRef   GPSTagID = 25 // ASCII. 'K' kilometers, 'M' miles, 'N' nautical miles
+	GPSTagDestDistance      GPSTagID = 26 // Rational
+	GPSTagProcessingMethod  GPSTagID = 27 // Undefined
+	GPSTagAreaInformation   GPSTagID = 28 // Undefined
+	GPSTagDateStamp         GPSTagID = 29 // ASCII. UTC date as YYYY:MM:DD
+	GPSTagDifferential      GPSTagID = 30 // Short. 1 with differential correction
+	GPSTagHPositioningError GPSTagID = 31 // Rational. Meters
+
 	// DNG 1.0
 	TagDNGVersion             TagID = 50706
 	TagDNGBackwardVersion     TagID = 50707
@@ -497,6 +563,8 @@ var tagName = map[TagID]string{
 	TagXMP:                    "XMP",
 	TagImageID:                "ImageID",
 	TagCopyright:              "Copyright",
+	TagIPTC:                   "IPTC",
+	TagPhotoshop:              "Photoshop",
 	TagImageLayer:             "ImageLayer",
 
 	// TIFF/EP
@@ -518,6 +586,18 @@ var tagName = map[TagID]string{
 	TagGPSIFD:              "GPSIFD",
 	TagInteroperabilityIFD: "InteroperabilityIFD",
 
+	// GeoTIFF
+	TagModelPixelScale:     "ModelPixelScale",
+	TagModelTiepoint:       "ModelTiepoint",
+	TagModelTransformation: "ModelTransformation",
+	TagGeoKeyDirectory:     "GeoKeyDirectory",
+	TagGeoDoubleParams:     "GeoDoubleParams",
+	TagGeoASCIIParams:      "GeoASCIIParams",
+
+	// GDAL
+	TagGDALMetadata: "GDALMetadata",
+	TagGDALNoData:   "GDALNoData",
+
 	// DNG 1.0
 	TagDNGVersion:             "DNGVersion",
 	TagDNGBackwardVersion:     "DNGBackwardVersion",
@@ -635,6 +715,9 @@ var exifTagName = map[ExifTagID]string{
 	ExifTagExifVersion:              "ExifVersion",
 	ExifTagDateTimeOriginal:         "DateTimeOriginal",
 	ExifTagDateTimeDigitized:        "DateTimeDigitized",
+	ExifTagOffsetTime:               "OffsetTime",
+	ExifTagOffsetTimeOriginal:       "OffsetTimeOriginal",
+	ExifTagOffsetTimeDigitized:      "OffsetTimeDigitized",
 	ExifTagComponentsConfiguration:  "ComponentsConfiguration",
 	ExifTagCompressedBitsPerPixel:   "CompressedBitsPerPixel",
 	ExifTagShutterSpeedValue:        "ShutterSpeedValue",
@@ -690,5 +773,38 @@ var exifTagName = map[ExifTagID]string{
 	ExifTagLensSerialNumber:         "LensSerialNumber",
 }
 
-var gpsTagName = map[GPSTagID]string{}
+var gpsTagName = map[GPSTagID]string{
+	GPSTagVersionID:         "VersionID",
+	GPSTagLatitudeRef:       "LatitudeRef",
+	GPSTagLatitude:          "Latitude",
+	GPSTagLongitudeRef:      "LongitudeRef",
+	GPSTagLongitude:         "Longitude",
+	GPSTagAltitudeRef:       "AltitudeRef",
+	GPSTagAltitude:          "Altitude",
+	GPSTagTimeStamp:         "TimeStamp",
+	GPSTagSatellites:        "Satellites",
+	GPSTagStatus:            "Status",
+	GPSTagMeasureMode:       "MeasureMode",
+	GPSTagDOP:               "DOP",
+	GPSTagSpeedRef:          "SpeedRef",
+	GPSTagSpeed:             "Speed",
+	GPSTagTrackRef:          "TrackRef",
+	GPSTagTrack:             "Track",
+	GPSTagImgDirectionRef:   "ImgDirectionRef",
+	GPSTagImgDirection:      "ImgDirection",
+	GPSTagMapDatum:          "MapDatum",
+	GPSTagDestLatitudeRef:   "DestLatitudeRef",
+	GPSTagDestLatitude:      "DestLatitude",
+	GPSTagDestLongitudeRef:  "DestLongitudeRef",
+	GPSTagDestLongitude:     "DestLongitude",
+	GPSTagDestBearingRef:    "DestBearingRef",
+	GPSTagDestBearing:       "DestBearing",
+	GPSTagDestDistanceRef:   "DestDistanceRef",
+	GPSTagDestDistance:      "DestDistance",
+	GPSTagProcessingMethod:  "ProcessingMethod",
+	GPSTagAreaInformation:   "AreaInformation",
+	GPSTagDateStamp:         "DateStamp",
+	GPSTagDifferential:      "Differential",
+	GPSTagHPositioningError: "HPositioningError",
+}
 var interoperabilityTagName = map[InteroperabilityTagID]string{}
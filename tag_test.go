@@ -0,0 +1,142 @@
+package tiff_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+func TestTag_Accessors(t *testing.T) {
+	header := &tiff.Header{ByteOrder: binary.BigEndian, Version: tiff.VersionClassicTIFF}
+	newTag := func(tagType tiff.TagType, value interface{}) *tiff.TagValue {
+		tag, err := tiff.NewTag(tiff.TagSoftware, tagType, value, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tag.Value()
+	}
+
+	rational := newTag(tiff.TagTypeRational, []tiff.Rational{{1, 3}, {5, 2}})
+	if v, ok := rational.RationalSlice(); !ok || !reflect.DeepEqual(v, []tiff.Rational{{1, 3}, {5, 2}}) {
+		t.Errorf("RationalSlice = %v, %v", v, ok)
+	}
+	if _, ok := rational.Rational(); ok {
+		t.Errorf("Rational of 2 values is ok")
+	}
+	if v, ok := newTag(tiff.TagTypeRational, tiff.Rational{5, 2}).Rational(); !ok || v.Float64() != 2.5 {
+		t.Errorf("Rational = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeSRational, []tiff.SRational{{-1, 4}}).SRational(); !ok || v.Float64() != -0.25 {
+		t.Errorf("SRational = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeFloat, []float32{1.5, 2}).FloatSlice(); !ok || !reflect.DeepEqual(v, []float32{1.5, 2}) {
+		t.Errorf("FloatSlice = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeFloat, float32(1.5)).Double(); !ok || v != 1.5 {
+		t.Errorf("Double of Float = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeDouble, []float64{0.1, 0.2}).DoubleSlice(); !ok || !reflect.DeepEqual(v, []float64{0.1, 0.2}) {
+		t.Errorf("DoubleSlice = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeSShort, []int16{-3, 7}).IntSlice(); !ok || !reflect.DeepEqual(v, []int{-3, 7}) {
+		t.Errorf("IntSlice = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeSLong8, int64(-1)<<40).Int64(); !ok || v != -1<<40 {
+		t.Errorf("Int64 = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeSLong, int32(-5)).Int(); !ok || v != -5 {
+		t.Errorf("Int = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeUndefined, []byte{1, 2, 3}).Bytes(); !ok || !reflect.DeepEqual(v, []byte{1, 2, 3}) {
+		t.Errorf("Bytes = %v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeByte, []byte{9}).Byte(); !ok || v != 9 {
+		t.Errorf("Byte = %v, %v", v, ok)
+	}
+
+	// Other data types
+	short := newTag(tiff.TagTypeShort, uint16(3))
+	if _, ok := short.Int(); ok {
+		t.Errorf("Int of Short is ok")
+	}
+	if _, ok := short.Double(); ok {
+		t.Errorf("Double of Short is ok")
+	}
+	if _, ok := short.RationalSlice(); ok {
+		t.Errorf("RationalSlice of Short is ok")
+	}
+
+	// Missing tags
+	var missing *tiff.Tag
+	if _, ok := missing.Value().Rational(); ok {
+		t.Errorf("Rational of nil Tag is ok")
+	}
+	if _, ok := missing.Value().Bytes(); ok {
+		t.Errorf("Bytes of nil Tag is ok")
+	}
+	if v, ok := missing.UintSlice(); ok || v == nil || len(v) != 0 {
+		t.Errorf("UintSlice of nil Tag = %#v, %v", v, ok)
+	}
+	if v, ok := newTag(tiff.TagTypeDouble, 1.5).UintSlice(); ok || v == nil || len(v) != 0 {
+		t.Errorf("UintSlice of Double = %#v, %v", v, ok)
+	}
+
+	// Data not matching Count and Type
+	invalid := &tiff.TagValue{Type: tiff.TagTypeRational, Count: 2, Data: make([]byte, 8), ByteOrder: binary.BigEndian}
+	if _, ok := invalid.RationalSlice(); ok {
+		t.Errorf("RationalSlice of 8 bytes is ok")
+	}
+	invalid = &tiff.TagValue{Type: tiff.TagTypeDouble, Count: 1, Data: make([]byte, 4), ByteOrder: binary.BigEndian}
+	if _, ok := invalid.Double(); ok {
+		t.Errorf("Double of 4 bytes is ok")
+	}
+	invalid = &tiff.TagValue{Type: tiff.TagTypeASCII, Count: 3, Data: []byte("a\x00"), ByteOrder: binary.BigEndian}
+	if _, ok := invalid.String(); ok {
+		t.Errorf("String of 2 bytes with Count 3 is ok")
+	}
+	if _, ok := (&tiff.TagValue{Type: tiff.TagTypeLong, Count: 1}).Uint(); ok {
+		t.Errorf("Uint without data is ok")
+	}
+
+	// Tags of other IFDs share the same accessors
+	exif := &tiff.ExifTag{Type: rational.Type, Count: rational.Count, Data: rational.Data, Header: header}
+	if v, ok := exif.Value().RationalSlice(); !ok || len(v) != 2 || v[1].Float64() != 2.5 {
+		t.Errorf("ExifTag.RationalSlice = %v, %v", v, ok)
+	}
+	gps := &tiff.GPSTag{Type: tiff.TagTypeByte, Count: 1, Data: []byte{1}, Header: header}
+	if v, ok := gps.Value().Byte(); !ok || v != 1 {
+		t.Errorf("GPSTag.Byte = %v, %v", v, ok)
+	}
+	var interoperability *tiff.InteroperabilityTag
+	if _, ok := interoperability.Value().String(); ok {
+		t.Errorf("String of nil InteroperabilityTag is ok")
+	}
+}
+
+func TestNewTag_Rational(t *testing.T) {
+	header := &tiff.Header{ByteOrder: binary.BigEndian, Version: tiff.VersionClassicTIFF}
+
+	rationals := []tiff.Rational{{1, 2}, {3, 4}, {5, 6}}
+	tag, err := tiff.NewTag(tiff.TagXResolution, tiff.TagTypeRational, rationals, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5, 0, 0, 0, 6}
+	if tag.Count != 3 || !reflect.DeepEqual(tag.Data, want) {
+		t.Errorf("Rational: Count = %d, Data = %v", tag.Count, tag.Data)
+	}
+	if got := tiff.DecodeRational(tag.Count, tag.Data, header.ByteOrder); !reflect.DeepEqual(got, rationals) {
+		t.Errorf("DecodeRational = %v, want %v", got, rationals)
+	}
+
+	srationals := []tiff.SRational{{-1, 2}, {3, -4}}
+	tag, err = tiff.NewTag(tiff.TagXResolution, tiff.TagTypeSRational, srationals, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tiff.DecodeSRational(tag.Count, tag.Data, header.ByteOrder); !reflect.DeepEqual(got, srationals) {
+		t.Errorf("DecodeSRational = %v, want %v", got, srationals)
+	}
+}
@@ -0,0 +1,307 @@
+package tiff
+
+import (
+	"encoding/binary"
+)
+
+// TagValue is the data of a tag of any IFD, decoded by typed accessors. It is returned by the Value method
+// of Tag, ExifTag, GPSTag and InteroperabilityTag, and is nil for missing tags.
+//
+// Accessors of single values return (zero, false) when the tag does not exist, does not have exactly one value,
+// or is of other data type. Accessors of slices return (nil, false) when the tag does not exist, has zero count,
+// or is of other data type. Both return false when the size of Data does not match Count and Type.
+type TagValue struct {
+	Type      TagType
+	Count     int
+	Data      []byte
+	ByteOrder binary.ByteOrder
+}
+
+// valid reports whether t has values, with Data of the size given by Count and Type.
+func (t *TagValue) valid() bool {
+	if t == nil || t.Count <= 0 {
+		return false
+	}
+	size := t.Type.Size()
+	return size > 0 && len(t.Data)%size == 0 && len(t.Data)/size == t.Count
+}
+
+// newTagValue returns the value of a tag, decoded in the byte order of header.
+func newTagValue(tagType TagType, count int, data []byte, header *Header) *TagValue {
+	v := &TagValue{Type: tagType, Count: count, Data: data, ByteOrder: binary.LittleEndian}
+	if header != nil {
+		v.ByteOrder = header.ByteOrder
+	}
+	return v
+}
+
+// String decodes an ASCII tag value as string. If the tag has several strings, the first one is returned.
+func (t *TagValue) String() (string, bool) {
+	v, ok := t.StringSlice()
+	if !ok {
+		return "", false
+	}
+	return v[0], true
+}
+
+// StringSlice decodes the strings of an ASCII tag value, which are separated by NUL.
+func (t *TagValue) StringSlice() ([]string, bool) {
+	if !t.valid() || t.Type != TagTypeASCII {
+		return nil, false
+	}
+	v, err := DecodeASCII(t.Count, t.Data, t.ByteOrder)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// Byte decodes a Byte or Undefined tag value as uint8, when the tag only has one value.
+func (t *TagValue) Byte() (uint8, bool) {
+	v, ok := t.Bytes()
+	if !ok || len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// Bytes returns the data of a Byte or Undefined tag.
+func (t *TagValue) Bytes() ([]byte, bool) {
+	if !t.valid() || (t.Type != TagTypeByte && t.Type != TagTypeUndefined) {
+		return nil, false
+	}
+	return t.Data, true
+}
+
+// Uint decodes Short, Long or Long8 tag value as uint, when the tag only has one value.
+func (t *TagValue) Uint() (uint, bool) {
+	v, ok := t.UintSlice()
+	if !ok || len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// UintSlice decodes Short, Long or Long8 tag values as []uint.
+//
+// Unlike other accessors of slices, it returns ([]uint{}, false) when the values cannot be decoded.
+func (t *TagValue) UintSlice() ([]uint, bool) {
+	if !t.valid() {
+		return []uint{}, false
+	}
+	var value []uint
+	switch t.Type {
+	case TagTypeShort:
+		for _, v := range DecodeShort(t.Count, t.Data, t.ByteOrder) {
+			value = append(value, uint(v))
+		}
+	case TagTypeLong, TagTypeIFD:
+		for _, v := range DecodeLong(t.Count, t.Data, t.ByteOrder) {
+			value = append(value, uint(v))
+		}
+	case TagTypeLong8, TagTypeIFD8:
+		for _, v := range DecodeLong8(t.Count, t.Data, t.ByteOrder) {
+			value = append(value, uint(v))
+		}
+	default:
+		return []uint{}, false
+	}
+	return value, true
+}
+
+// Int decodes SByte, SShort, SLong or SLong8 tag value as int, when the tag only has one value.
+func (t *TagValue) Int() (int, bool) {
+	v, ok := t.Int64()
+	if !ok || v != int64(int(v)) {
+		return 0, false
+	}
+	return int(v), true
+}
+
+// IntSlice decodes SByte, SShort, SLong or SLong8 tag values as []int.
+func (t *TagValue) IntSlice() ([]int, bool) {
+	v, ok := t.Int64Slice()
+	if !ok {
+		return nil, false
+	}
+	value := make([]int, len(v))
+	for i := range v {
+		if v[i] != int64(int(v[i])) {
+			return nil, false
+		}
+		value[i] = int(v[i])
+	}
+	return value, true
+}
+
+// Int64 decodes SByte, SShort, SLong or SLong8 tag value as int64, when the tag only has one value.
+func (t *TagValue) Int64() (int64, bool) {
+	v, ok := t.Int64Slice()
+	if !ok || len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// Int64Slice decodes SByte, SShort, SLong or SLong8 tag values as []int64.
+func (t *TagValue) Int64Slice() ([]int64, bool) {
+	if !t.valid() {
+		return nil, false
+	}
+	var value []int64
+	switch t.Type {
+	case TagTypeSByte:
+		for _, v := range DecodeSByte(t.Count, t.Data, t.ByteOrder) {
+			value = append(value, int64(v))
+		}
+	case TagTypeSShort:
+		for _, v := range DecodeSShort(t.Count, t.Data, t.ByteOrder) {
+			value = append(value, int64(v))
+		}
+	case TagTypeSLong:
+		for _, v := range DecodeSLong(t.Count, t.Data, t.ByteOrder) {
+			value = append(value, int64(v))
+		}
+	case TagTypeSLong8:
+		value = DecodeSLong8(t.Count, t.Data, t.ByteOrder)
+	default:
+		return nil, false
+	}
+	return value, true
+}
+
+// Float decodes a Float tag value as float32, when the tag only has one value.
+func (t *TagValue) Float() (float32, bool) {
+	v, ok := t.FloatSlice()
+	if !ok || len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// FloatSlice decodes Float tag values as []float32.
+func (t *TagValue) FloatSlice() ([]float32, bool) {
+	if !t.valid() || t.Type != TagTypeFloat {
+		return nil, false
+	}
+	return DecodeFloat(t.Count, t.Data, t.ByteOrder), true
+}
+
+// Double decodes a Float or Double tag value as float64, when the tag only has one value.
+func (t *TagValue) Double() (float64, bool) {
+	v, ok := t.DoubleSlice()
+	if !ok || len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// DoubleSlice decodes Float or Double tag values as []float64.
+func (t *TagValue) DoubleSlice() ([]float64, bool) {
+	if !t.valid() {
+		return nil, false
+	}
+	switch t.Type {
+	case TagTypeFloat:
+		v := DecodeFloat(t.Count, t.Data, t.ByteOrder)
+		value := make([]float64, len(v))
+		for i := range v {
+			value[i] = float64(v[i])
+		}
+		return value, true
+	case TagTypeDouble:
+		return DecodeDouble(t.Count, t.Data, t.ByteOrder), true
+	default:
+		return nil, false
+	}
+}
+
+// Rational decodes a Rational tag value, when the tag only has one value.
+func (t *TagValue) Rational() (Rational, bool) {
+	v, ok := t.RationalSlice()
+	if !ok || len(v) != 1 {
+		return Rational{}, false
+	}
+	return v[0], true
+}
+
+// RationalSlice decodes Rational tag values.
+func (t *TagValue) RationalSlice() ([]Rational, bool) {
+	if !t.valid() || t.Type != TagTypeRational {
+		return nil, false
+	}
+	return DecodeRational(t.Count, t.Data, t.ByteOrder), true
+}
+
+// SRational decodes an SRational tag value, when the tag only has one value.
+func (t *TagValue) SRational() (SRational, bool) {
+	v, ok := t.SRationalSlice()
+	if !ok || len(v) != 1 {
+		return SRational{}, false
+	}
+	return v[0], true
+}
+
+// SRationalSlice decodes SRational tag values.
+func (t *TagValue) SRationalSlice() ([]SRational, bool) {
+	if !t.valid() || t.Type != TagTypeSRational {
+		return nil, false
+	}
+	return DecodeSRational(t.Count, t.Data, t.ByteOrder), true
+}
+
+// Value returns the value of the tag for typed accessors, or nil if the tag is nil.
+func (t *Tag) Value() *TagValue {
+	if t == nil {
+		return nil
+	}
+	return newTagValue(t.Type, t.Count, t.Data, t.Header)
+}
+
+// String decodes an ASCII tag value as string. If the tag has several strings, the first one is returned.
+func (t *Tag) String() (value string, ok bool) {
+	return t.Value().String()
+}
+
+// StringSlice decodes the strings of an ASCII tag value, which are separated by NUL.
+func (t *Tag) StringSlice() (value []string, ok bool) {
+	return t.Value().StringSlice()
+}
+
+// Uint decodes Short, Long or Long8 tag value as uint, when the tag only has one value.
+//
+// It returns (0, false) when the tags does not exist, has zero count or multiple values, or is of other data type.
+func (t *Tag) Uint() (value uint, ok bool) {
+	return t.Value().Uint()
+}
+
+// UintSlice decodes Short, Long or Long8 tag values as []uint.
+//
+// It returns ([]uint{}, false) when the tags does not exist, has zero-count, or is of other data type.
+func (t *Tag) UintSlice() (value []uint, ok bool) {
+	return t.Value().UintSlice()
+}
+
+// Value returns the value of the tag for typed accessors, or nil if the tag is nil.
+func (t *ExifTag) Value() *TagValue {
+	if t == nil {
+		return nil
+	}
+	return newTagValue(t.Type, t.Count, t.Data, t.Header)
+}
+
+// Value returns the value of the tag for typed accessors, or nil if the tag is nil.
+func (t *GPSTag) Value() *TagValue {
+	if t == nil {
+		return nil
+	}
+	return newTagValue(t.Type, t.Count, t.Data, t.Header)
+}
+
+// Value returns the value of the tag for typed accessors, or nil if the tag is nil.
+func (t *InteroperabilityTag) Value() *TagValue {
+	if t == nil {
+		return nil
+	}
+	return newTagValue(t.Type, t.Count, t.Data, t.Header)
+}
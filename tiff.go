@@ -27,6 +27,11 @@ To iterate through images in a multi-page TIFF and read image data:
 		// handle error
 	}
 
+Importing the package registers the "tiff" format with the image package,
+so that image.Decode and image.DecodeConfig accept TIFF and BigTIFF files.
+A decoded Image can also be converted to an image.Image directly:
+	img, err := im.GoImage()
+
 To read SubIFDs:
 	if im.SubImage != nil {
 		for _, subim := range im.SubImage {
@@ -34,14 +39,71 @@ To read SubIFDs:
 		}
 	}
 
+To read an image at the resolution of its overviews best suited for display:
+	pyramids, err := r.Pyramids()
+	p := pyramids[0]
+	level := p.BestLevel(800, 600)
+	// Or decode a region of the full resolution image from the best level
+	level, levelRect, buf, err := p.DecodeRegion(rect, 800, 600, nil)
+
+To read the georeferencing of a GeoTIFF image:
+	keys, err := im.GeoKeys()
+	epsg, ok := keys.EPSG()
+	citation, ok := keys.Citation()
+	transform, ok := im.GeoTransform()
+	x, y := transform.Apply(column, row)
+
 To read any TIFF tag, EXIF tag or GPS tag:
 	bisPerSample, ok := im.Tag[tiff.TagBitsPerSample].UintSlice()
 	make, ok := im.Tag[tiff.TagMake].String()
 	model, ok := im.Tag[tiff.TagModel].String()
 
-	exposure, ok := im.Exif.Tag[tiff.TagExposureTime].Rational()
-	exposureSecond := float64(exposure[0]) / float64(exposure[1])
-	lensModel, ok := im.Exif.Tag[tiff.TagLensModel].String()
+	exposure, ok := im.Exif.Tag[tiff.ExifTagExposureTime].Value().Rational()
+	exposureSecond := exposure.Float64()
+	lensModel, ok := im.Exif.Tag[tiff.ExifTagLensModel].Value().String()
+	altitude, ok := im.GPS.Tag[tiff.GPSTagAltitude].Value().Rational()
+
+Or decode common Exif tags at once:
+	info := im.ExifInfo()
+	fmt.Println(info.Model, info.ExposureTime, info.FNumber, info.ISO, info.DateTimeOriginal)
+
+To decode the maker note of Canon, Fujifilm, Nikon, Olympus, Panasonic, Pentax or Sony cameras:
+	mn, err := im.MakerNote()
+	shutterCount, ok := mn.ShutterCount()
+	for id, tag := range mn.Tag {
+		fmt.Println(mn.TagName(id), tag)
+	}
+
+To read and write XMP metadata:
+	x, err := im.XMP()
+	title, ok := x.Title()
+	keywords, ok := x.Subjects()
+	rating, ok := x.Int(tiff.NamespaceXMP, "Rating")
+
+	if x == nil {
+		x = &tiff.XMP{} // The image has no XMP packet
+	}
+	x.SetSubjects([]string{"bridge", "river"})
+	err = im.SetXMP(x)
+
+To read and write IPTC-IIM metadata:
+	iptc, err := im.IPTC()
+	caption, ok := iptc.Caption()
+	keywords := iptc.Keywords()
+
+	if iptc == nil {
+		iptc = &tiff.IPTC{} // The image has no IPTC-IIM records
+	}
+	err = iptc.Set(tiff.IPTCKeywords, "news", "sport")
+	err = im.SetIPTC(iptc)
+
+To read and write GPS coordinates:
+	latitude, longitude, ok := im.GPS.LatLong()
+	altitude, ok := im.GPS.Altitude()
+	t, ok := im.GPS.Time()
+
+	err = im.SetGPSLatLong(latitude, longitude)
+	err = im.SetGPSTime(t)
 
 To encode a TIFF image with one or multiple pages:
 	enc, err := tiff.NewEncoder(f)
@@ -51,6 +113,8 @@ To encode a TIFF image with one or multiple pages:
 	// Default:
 	// enc.SetByteOrder(binary.LittleEndian)
 	// enc.SetVersion(tiff.VersionClassicTIFF)
+	// Use tiff.VersionBigTIFF for files larger than 4 GiB, or tiff.VersionAuto
+	// to choose from the size of the first image
 	im := enc.NewImage()
 	im.SetWidthHeight(400, 300)
 	im.SetPixelFormat(tiff.PhotometricRGB, 3, []uint16{8, 8, 8})
@@ -61,7 +125,7 @@ To encode a TIFF image with one or multiple pages:
 	im.SetTag(tiff.TagXResolution, tiff.TagTypeRational, [2]uint32{})
 	im.SetTag(tiff.TagYResolution, tiff.TagTypeRational, [2]uint32{})
 	im.SetTag(tiff.TagResolutionUnit, , tiff.TagTypeShort, 1)
-	im.SetExifTag(tiff.ExifTagExposureTime, tiff.TagTypeRational, [2]uint32{})
+	im.SetExifTag(tiff.ExifTagExposureTime, tiff.TagTypeRational, tiff.NewRational(1, 250))
 	err = im.EncodeImage(buf)
 
 	// To write another image
@@ -71,6 +135,68 @@ To encode a TIFF image with one or multiple pages:
 
 	w.Close()
 
+To encode to an io.Writer that cannot seek, such as a pipe or an HTTP response:
+	enc := tiff.NewStreamEncoder(w)
+	enc.SetStreamBufferSize(1 << 30) // Compressed image data are kept in memory until the image is complete
+	// ...
+	err = enc.Close() // Writes the last image
+
+To write image data at increasing offsets to an io.WriterAt, with all IFDs at the end of the file:
+	enc := tiff.NewEncoderAt(f)
+	// ...
+	err = enc.Close() // Writes the IFDs and the header
+
+To write a Cloud Optimized GeoTIFF, with all IFDs at the beginning of the file and tiles of overviews
+before tiles of the full resolution image:
+	enc.SetLayout(tiff.LayoutCOG)
+	// Encode the tiled full resolution image, then its overviews
+	err = enc.Close() // Writes the file
+
+To encode an image larger than memory row by row:
+	rw, err := im.NewRowWriter()
+	for ... {
+		err = rw.WriteRows(rows)
+	}
+	err = rw.Close()
+
+To encode the tiles of a tiled image in any order, possibly from multiple goroutines:
+	im.SetTileWidthHeight(256, 256)
+	tw, err := im.NewTileWriter()
+	err = tw.WriteTile(ix, iy, tile)
+	// Tiles that are not written are recorded as sparse tiles
+	err = tw.Close()
+
+To encode an image.Image:
+	im := enc.NewImage()
+	err = im.EncodeGoImage(img)
+
+To build overviews (reduced-resolution images) when encoding an image:
+	im.SetOverviews(&tiff.OverviewOptions{
+		Resampling: tiff.ResamplingAverage,
+		// SubIFDs: true, // Overviews as SubIFDs instead of IFDs following the image
+	})
+	err = im.EncodeImage(buf)
+
+To georeference an image before encoding it:
+	err = im.SetGeoTransform(tiff.GeoTransform{originX, pixelWidth, 0, originY, 0, -pixelHeight})
+	err = im.SetEPSG(tiff.ModelTypeProjected, 32633)
+	// Or set any GeoKeys
+	keys := &tiff.GeoKeys{}
+	keys.SetShort(tiff.GeoKeyGTModelType, tiff.ModelTypeGeographic)
+	keys.SetASCII(tiff.GeoKeyGTCitation, "WGS 84")
+	err = im.SetGeoKeys(keys)
+
+To read and write GDAL metadata, such as band statistics and nodata:
+	m, err := im.GDALMetadata()
+	mean, ok := m.Float(0, "STATISTICS_MEAN")
+	scale, offset, ok := m.ScaleOffset(0)
+	noData, ok := im.GDALNoData()
+
+	m := &tiff.GDALMetadata{}
+	m.Set("", 0, "DESCRIPTION", "Red")
+	err = im.SetGDALMetadata(m)
+	err = im.SetGDALNoData(-9999)
+
 To encode a TIFF image with sub-images (SubIFDs).
 	im := w.NewImage()
 	// ...
@@ -239,6 +365,12 @@ type Version uint16
 const (
 	VersionClassicTIFF Version = 42
 	VersionBigTIFF     Version = 43
+
+	// VersionAuto lets the Encoder choose between ClassicTIFF and BigTIFF based on the size of the first image
+	// and its overviews.
+	// Files with several images which exceed 4 GiB together require VersionBigTIFF.
+	// It is never found in a file.
+	VersionAuto Version = 0
 )
 
 func (v Version) String() string {
@@ -247,6 +379,8 @@ func (v Version) String() string {
 		return "ClassicTIFF"
 	case VersionBigTIFF:
 		return "BigTIFF"
+	case VersionAuto:
+		return "Auto"
 	default:
 		return strconv.Itoa(int(v))
 	}
@@ -262,6 +396,14 @@ func (r Rational) String() string {
 	return fmt.Sprintf("%d/%d", r[0], r[1])
 }
 
+// Float64 returns the value of the fraction, or NaN if its denominator is 0.
+func (r Rational) Float64() float64 {
+	if r[1] == 0 {
+		return math.NaN()
+	}
+	return float64(r[0]) / float64(r[1])
+}
+
 type SRational [2]int32
 
 func NewSRational(a int32, b int32) SRational {
@@ -272,6 +414,14 @@ func (r SRational) String() string {
 	return fmt.Sprintf("%d/%d", r[0], r[1])
 }
 
+// Float64 returns the value of the fraction, or NaN if its denominator is 0.
+func (r SRational) Float64() float64 {
+	if r[1] == 0 {
+		return math.NaN()
+	}
+	return float64(r[0]) / float64(r[1])
+}
+
 type DataType int
 
 const (
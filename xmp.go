@@ -0,0 +1,596 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Namespaces of XMP properties
+const (
+	NamespaceRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NamespaceDC        = "http://purl.org/dc/elements/1.1/"
+	NamespaceXMP       = "http://ns.adobe.com/xap/1.0/"
+	NamespaceXMPRights = "http://ns.adobe.com/xap/1.0/rights/"
+	NamespacePhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+	NamespaceTIFF      = "http://ns.adobe.com/tiff/1.0/"
+	NamespaceExif      = "http://ns.adobe.com/exif/1.0/"
+
+	namespaceXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// Types of XMP arrays
+const (
+	XMPBag = "Bag" // Unordered array
+	XMPSeq = "Seq" // Ordered array
+	XMPAlt = "Alt" // Alternatives, such as the languages of a text
+)
+
+// XMPDefaultLanguage is the language of the default item of language alternatives.
+const XMPDefaultLanguage = "x-default"
+
+// xmpPrefix are the usual prefixes of namespaces, used when serializing XMP.
+var xmpPrefix = map[string]string{
+	NamespaceRDF:       "rdf",
+	NamespaceDC:        "dc",
+	NamespaceXMP:       "xmp",
+	NamespaceXMPRights: "xmpRights",
+	NamespacePhotoshop: "photoshop",
+	NamespaceTIFF:      "tiff",
+	NamespaceExif:      "exif",
+}
+
+// XMPProperty is a property of an XMP packet, or an item of an array.
+//
+// A property is either simple, with a Value, a struct, with Fields, or an array of type ArrayType, with Items.
+// Items of arrays have no namespace and name.
+type XMPProperty struct {
+	Namespace string
+	Name      string
+	Value     string
+	Lang      string // Language of the value, e.g. items of XMPAlt arrays
+	Fields    []*XMPProperty
+	ArrayType string // XMPBag, XMPSeq, XMPAlt, or empty if the property is not an array
+	Items     []*XMPProperty
+}
+
+// IsStruct reports whether the property is a struct.
+func (p *XMPProperty) IsStruct() bool {
+	return p.Fields != nil
+}
+
+// Field returns the field of a struct property, or nil.
+func (p *XMPProperty) Field(namespace string, name string) *XMPProperty {
+	if p == nil {
+		return nil
+	}
+	for _, f := range p.Fields {
+		if f.Namespace == namespace && f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Text returns the value of a simple property, or the default item of a language alternative.
+func (p *XMPProperty) Text() (string, bool) {
+	switch {
+	case p == nil || p.IsStruct():
+		return "", false
+	case p.ArrayType == "":
+		return p.Value, true
+	case len(p.Items) == 0:
+		return "", false
+	}
+	for _, item := range p.Items {
+		if item.Lang == XMPDefaultLanguage {
+			return item.Text()
+		}
+	}
+	return p.Items[0].Text()
+}
+
+// Strings returns the values of the items of an array, or the value of a simple property.
+func (p *XMPProperty) Strings() ([]string, bool) {
+	if p == nil || p.IsStruct() {
+		return nil, false
+	}
+	if p.ArrayType == "" {
+		return []string{p.Value}, true
+	}
+	values := make([]string, 0, len(p.Items))
+	for _, item := range p.Items {
+		if v, ok := item.Text(); ok {
+			values = append(values, v)
+		}
+	}
+	return values, true
+}
+
+// XMP is the metadata of an XMP packet, stored in TagXMP.
+type XMP struct {
+	Properties []*XMPProperty
+	Prefix     map[string]string // Prefixes of namespaces in the packet
+}
+
+// XMP parses the XMP packet of the image.
+//
+// It returns (nil, nil) when the image has no XMP packet.
+func (im *Image) XMP() (*XMP, error) {
+	tag := im.Tag[TagXMP]
+	if tag == nil {
+		return nil, nil
+	}
+	x, err := ParseXMP(tag.Data)
+	if err != nil {
+		return nil, FormatError(fmt.Sprintf("XMP: %v", err))
+	}
+	return x, nil
+}
+
+// SetXMP sets TagXMP to the packet of x, with 2 KB of padding for in-place editing.
+func (im *Image) SetXMP(x *XMP) error {
+	if x == nil {
+		return fmt.Errorf("cannot set a nil XMP packet")
+	}
+	return im.SetTag(TagXMP, TagTypeByte, x.Encode(2048))
+}
+
+// xmlNode is an element of an XML document.
+type xmlNode struct {
+	name     xml.Name
+	attr     []xml.Attr
+	children []*xmlNode
+	text     string
+}
+
+// attrValue returns the value of an attribute of the node.
+func (n *xmlNode) attrValue(space string, local string) (string, bool) {
+	for _, a := range n.attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// find returns the first node named name in the tree of n, searched depth-first.
+func (n *xmlNode) find(name xml.Name) *xmlNode {
+	if n.name == name {
+		return n
+	}
+	for _, c := range n.children {
+		if found := c.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// ParseXMP parses an XMP packet.
+func ParseXMP(data []byte) (*XMP, error) {
+	data = bytes.TrimRight(data, "\x00")
+	x := &XMP{Prefix: make(map[string]string)}
+
+	//
+	// Build the tree of XML elements
+	//
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: token.Name, attr: token.Copy().Attr}
+			for _, a := range token.Attr {
+				if a.Name.Space == "xmlns" {
+					x.Prefix[a.Value] = a.Name.Local
+				}
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			node := stack[len(stack)-1]
+			node.text += string(token)
+		}
+	}
+
+	//
+	// Properties of all rdf:Description elements of rdf:RDF
+	//
+	rdf := root.find(xml.Name{Space: NamespaceRDF, Local: "RDF"})
+	if rdf == nil {
+		return nil, fmt.Errorf("no rdf:RDF element")
+	}
+	for _, desc := range rdf.children {
+		if desc.name != (xml.Name{Space: NamespaceRDF, Local: "Description"}) {
+			continue
+		}
+		x.Properties = append(x.Properties, xmpFields(desc)...)
+	}
+	return x, nil
+}
+
+// xmpFields returns the properties of a node, from its attributes and its child elements.
+func xmpFields(n *xmlNode) []*XMPProperty {
+	fields := []*XMPProperty{}
+	for _, a := range n.attr {
+		switch a.Name.Space {
+		case "", "xmlns", NamespaceRDF, namespaceXML:
+			continue
+		}
+		fields = append(fields, &XMPProperty{Namespace: a.Name.Space, Name: a.Name.Local, Value: a.Value})
+	}
+	for _, c := range n.children {
+		fields = append(fields, xmpProperty(c))
+	}
+	return fields
+}
+
+// xmpProperty returns the property of an element, which may be an rdf:li item.
+func xmpProperty(n *xmlNode) *XMPProperty {
+	p := &XMPProperty{Namespace: n.name.Space, Name: n.name.Local}
+	if n.name == (xml.Name{Space: NamespaceRDF, Local: "li"}) {
+		p.Namespace, p.Name = "", ""
+	}
+	p.Lang, _ = n.attrValue(namespaceXML, "lang")
+
+	if v, ok := n.attrValue(NamespaceRDF, "resource"); ok {
+		p.Value = v
+		return p
+	}
+	if v, _ := n.attrValue(NamespaceRDF, "parseType"); v == "Resource" {
+		p.Fields = xmpFields(&xmlNode{children: n.children})
+		return p
+	}
+	if len(n.children) == 1 && n.children[0].name.Space == NamespaceRDF {
+		c := n.children[0]
+		switch c.name.Local {
+		case XMPBag, XMPSeq, XMPAlt:
+			p.ArrayType = c.name.Local
+			p.Items = []*XMPProperty{}
+			for _, li := range c.children {
+				p.Items = append(p.Items, xmpProperty(li))
+			}
+			return p
+		case "Description":
+			p.Fields = xmpFields(c)
+			return p
+		}
+	}
+	if len(n.children) > 0 {
+		p.Fields = xmpFields(&xmlNode{children: n.children})
+		return p
+	}
+	// Struct whose fields are attributes
+	if fields := xmpFields(&xmlNode{attr: n.attr}); len(fields) > 0 {
+		p.Fields = fields
+		return p
+	}
+	p.Value = n.text
+	return p
+}
+
+// Property returns a top-level property, or nil.
+func (x *XMP) Property(namespace string, name string) *XMPProperty {
+	if x == nil {
+		return nil
+	}
+	for _, p := range x.Properties {
+		if p.Namespace == namespace && p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Text returns the value of a simple property, or the default item of a language alternative.
+func (x *XMP) Text(namespace string, name string) (string, bool) {
+	return x.Property(namespace, name).Text()
+}
+
+// Strings returns the values of the items of an array property.
+func (x *XMP) Strings(namespace string, name string) ([]string, bool) {
+	return x.Property(namespace, name).Strings()
+}
+
+// Int returns the value of a simple property as int.
+func (x *XMP) Int(namespace string, name string) (int, bool) {
+	s, ok := x.Text(namespace, name)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// xmpTimeLayouts are the formats of XMP dates, from the most to the least precise.
+var xmpTimeLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// Time returns the value of a date property. Without a time zone, the date is in UTC.
+func (x *XMP) Time(namespace string, name string) (time.Time, bool) {
+	s, ok := x.Text(namespace, name)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range xmpTimeLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Set sets a top-level property, replacing the property of the same namespace and name.
+func (x *XMP) Set(p *XMPProperty) {
+	for i, old := range x.Properties {
+		if old.Namespace == p.Namespace && old.Name == p.Name {
+			x.Properties[i] = p
+			return
+		}
+	}
+	x.Properties = append(x.Properties, p)
+}
+
+// SetText sets a simple property.
+func (x *XMP) SetText(namespace string, name string, value string) {
+	x.Set(&XMPProperty{Namespace: namespace, Name: name, Value: value})
+}
+
+// SetLangAlt sets a language alternative with only a default item.
+func (x *XMP) SetLangAlt(namespace string, name string, value string) {
+	x.Set(&XMPProperty{Namespace: namespace, Name: name, ArrayType: XMPAlt, Items: []*XMPProperty{
+		{Value: value, Lang: XMPDefaultLanguage},
+	}})
+}
+
+// SetArray sets an array of simple values, whose type is XMPBag, XMPSeq or XMPAlt.
+func (x *XMP) SetArray(namespace string, name string, arrayType string, values []string) {
+	p := &XMPProperty{Namespace: namespace, Name: name, ArrayType: arrayType, Items: make([]*XMPProperty, len(values))}
+	for i, v := range values {
+		p.Items[i] = &XMPProperty{Value: v}
+	}
+	x.Set(p)
+}
+
+// SetTime sets a date property.
+func (x *XMP) SetTime(namespace string, name string, t time.Time) {
+	x.SetText(namespace, name, t.Format(time.RFC3339Nano))
+}
+
+// Delete removes a top-level property.
+func (x *XMP) Delete(namespace string, name string) {
+	for i, p := range x.Properties {
+		if p.Namespace == namespace && p.Name == name {
+			x.Properties = append(x.Properties[:i], x.Properties[i+1:]...)
+			return
+		}
+	}
+}
+
+// Title returns dc:title.
+func (x *XMP) Title() (string, bool) {
+	return x.Text(NamespaceDC, "title")
+}
+
+// Description returns dc:description, e.g. the caption of the image.
+func (x *XMP) Description() (string, bool) {
+	return x.Text(NamespaceDC, "description")
+}
+
+// Creators returns dc:creator, the authors of the image.
+func (x *XMP) Creators() ([]string, bool) {
+	return x.Strings(NamespaceDC, "creator")
+}
+
+// Subjects returns dc:subject, the keywords of the image.
+func (x *XMP) Subjects() ([]string, bool) {
+	return x.Strings(NamespaceDC, "subject")
+}
+
+// Rights returns dc:rights, the copyright notice.
+func (x *XMP) Rights() (string, bool) {
+	return x.Text(NamespaceDC, "rights")
+}
+
+// CreatorTool returns xmp:CreatorTool, the software which created the image.
+func (x *XMP) CreatorTool() (string, bool) {
+	return x.Text(NamespaceXMP, "CreatorTool")
+}
+
+// Rating returns xmp:Rating, from -1 (rejected) and 0 (unrated) to 5.
+func (x *XMP) Rating() (int, bool) {
+	return x.Int(NamespaceXMP, "Rating")
+}
+
+// CreateDate returns xmp:CreateDate.
+func (x *XMP) CreateDate() (time.Time, bool) {
+	return x.Time(NamespaceXMP, "CreateDate")
+}
+
+// ModifyDate returns xmp:ModifyDate.
+func (x *XMP) ModifyDate() (time.Time, bool) {
+	return x.Time(NamespaceXMP, "ModifyDate")
+}
+
+// Headline returns photoshop:Headline.
+func (x *XMP) Headline() (string, bool) {
+	return x.Text(NamespacePhotoshop, "Headline")
+}
+
+// City returns photoshop:City.
+func (x *XMP) City() (string, bool) {
+	return x.Text(NamespacePhotoshop, "City")
+}
+
+// Country returns photoshop:Country.
+func (x *XMP) Country() (string, bool) {
+	return x.Text(NamespacePhotoshop, "Country")
+}
+
+// Credit returns photoshop:Credit.
+func (x *XMP) Credit() (string, bool) {
+	return x.Text(NamespacePhotoshop, "Credit")
+}
+
+// Make returns tiff:Make, the camera manufacturer.
+func (x *XMP) Make() (string, bool) {
+	return x.Text(NamespaceTIFF, "Make")
+}
+
+// Model returns tiff:Model, the camera model.
+func (x *XMP) Model() (string, bool) {
+	return x.Text(NamespaceTIFF, "Model")
+}
+
+// DateTimeOriginal returns exif:DateTimeOriginal.
+func (x *XMP) DateTimeOriginal() (time.Time, bool) {
+	return x.Time(NamespaceExif, "DateTimeOriginal")
+}
+
+// SetTitle sets dc:title.
+func (x *XMP) SetTitle(title string) {
+	x.SetLangAlt(NamespaceDC, "title", title)
+}
+
+// SetDescription sets dc:description.
+func (x *XMP) SetDescription(description string) {
+	x.SetLangAlt(NamespaceDC, "description", description)
+}
+
+// SetCreators sets dc:creator.
+func (x *XMP) SetCreators(creators []string) {
+	x.SetArray(NamespaceDC, "creator", XMPSeq, creators)
+}
+
+// SetSubjects sets dc:subject.
+func (x *XMP) SetSubjects(subjects []string) {
+	x.SetArray(NamespaceDC, "subject", XMPBag, subjects)
+}
+
+// Encode serializes x as an XMP packet, followed by padding bytes of white space
+// which allow to edit the packet in place.
+func (x *XMP) Encode(padding int) []byte {
+	//
+	// Prefixes of namespaces
+	//
+	prefix := make(map[string]string)
+	used := map[string]bool{"x": true, "rdf": true, "xml": true}
+	prefix[NamespaceRDF] = "rdf"
+	var namespaces []string
+	var addNamespace func(p *XMPProperty)
+	addNamespace = func(p *XMPProperty) {
+		if _, ok := prefix[p.Namespace]; !ok && p.Namespace != "" {
+			name, ok := x.Prefix[p.Namespace]
+			if !ok || used[name] {
+				name, ok = xmpPrefix[p.Namespace]
+			}
+			for i := 1; !ok || used[name]; i++ {
+				name, ok = fmt.Sprintf("ns%d", i), true
+			}
+			prefix[p.Namespace] = name
+			used[name] = true
+			namespaces = append(namespaces, p.Namespace)
+		}
+		for _, f := range p.Fields {
+			addNamespace(f)
+		}
+		for _, item := range p.Items {
+			addNamespace(item)
+		}
+	}
+	for _, p := range x.Properties {
+		addNamespace(p)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return prefix[namespaces[i]] < prefix[namespaces[j]] })
+
+	//
+	// Packet
+	//
+	b := &bytes.Buffer{}
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"" + NamespaceRDF + "\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"")
+	for _, ns := range namespaces {
+		b.WriteString("\n    xmlns:" + prefix[ns] + "=\"")
+		xml.EscapeText(b, []byte(ns))
+		b.WriteString("\"")
+	}
+	b.WriteString(">\n")
+	for _, p := range x.Properties {
+		writeXMPProperty(b, p, prefix, "   ")
+	}
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	for padding > 0 {
+		n := padding
+		if n > 100 {
+			n = 100
+		}
+		b.WriteString(strings.Repeat(" ", n-1) + "\n")
+		padding -= n
+	}
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// writeXMPProperty writes the element of a property, or an rdf:li element for an item of an array.
+func writeXMPProperty(b *bytes.Buffer, p *XMPProperty, prefix map[string]string, indent string) {
+	name := "rdf:li"
+	if p.Namespace != "" {
+		name = prefix[p.Namespace] + ":" + p.Name
+	}
+	b.WriteString(indent + "<" + name)
+	if p.Lang != "" {
+		b.WriteString(" xml:lang=\"")
+		xml.EscapeText(b, []byte(p.Lang))
+		b.WriteString("\"")
+	}
+	switch {
+	case p.ArrayType != "":
+		b.WriteString(">\n" + indent + " <rdf:" + p.ArrayType + ">\n")
+		for _, item := range p.Items {
+			writeXMPProperty(b, item, prefix, indent+"  ")
+		}
+		b.WriteString(indent + " </rdf:" + p.ArrayType + ">\n")
+		b.WriteString(indent + "</" + name + ">\n")
+	case p.IsStruct():
+		b.WriteString(" rdf:parseType=\"Resource\">\n")
+		for _, f := range p.Fields {
+			writeXMPProperty(b, f, prefix, indent+" ")
+		}
+		b.WriteString(indent + "</" + name + ">\n")
+	default:
+		b.WriteString(">")
+		xml.EscapeText(b, []byte(p.Value))
+		b.WriteString("</" + name + ">\n")
+	}
+}
@@ -0,0 +1,162 @@
+package tiff_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	tiff "github.com/Andeling/tiff"
+)
+
+const testXMP = `<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/"
+    xmp:Rating="4"
+    xmp:CreateDate="2021-07-14T10:30:15.25+02:00"
+    photoshop:City="Lyon"/>
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:Iptc4xmpCore="http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/">
+   <dc:title>
+    <rdf:Alt>
+     <rdf:li xml:lang="fr">Le pont</rdf:li>
+     <rdf:li xml:lang="x-default">The bridge</rdf:li>
+    </rdf:Alt>
+   </dc:title>
+   <dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li></rdf:Seq></dc:creator>
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>bridge</rdf:li>
+     <rdf:li>river &amp; city</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+   <Iptc4xmpCore:CreatorContactInfo rdf:parseType="Resource">
+    <Iptc4xmpCore:CiAdrCity>Lyon</Iptc4xmpCore:CiAdrCity>
+    <Iptc4xmpCore:CiEmailWork>jane@example.com</Iptc4xmpCore:CiEmailWork>
+   </Iptc4xmpCore:CreatorContactInfo>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+const namespaceIptcCore = "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"
+
+func checkXMP(t *testing.T, x *tiff.XMP) {
+	t.Helper()
+	if v, ok := x.Title(); !ok || v != "The bridge" {
+		t.Errorf("Title = %q, %v", v, ok)
+	}
+	if v, ok := x.Creators(); !ok || !reflect.DeepEqual(v, []string{"Jane Doe"}) {
+		t.Errorf("Creators = %q, %v", v, ok)
+	}
+	if v, ok := x.Subjects(); !ok || !reflect.DeepEqual(v, []string{"bridge", "river & city"}) {
+		t.Errorf("Subjects = %q, %v", v, ok)
+	}
+	if v, ok := x.Rating(); !ok || v != 4 {
+		t.Errorf("Rating = %d, %v", v, ok)
+	}
+	want := time.Date(2021, 7, 14, 8, 30, 15, 250e6, time.UTC)
+	if v, ok := x.CreateDate(); !ok || !v.Equal(want) {
+		t.Errorf("CreateDate = %v, %v", v, ok)
+	}
+	if v, ok := x.City(); !ok || v != "Lyon" {
+		t.Errorf("City = %q, %v", v, ok)
+	}
+	if _, ok := x.Headline(); ok {
+		t.Errorf("Headline found")
+	}
+	contact := x.Property(namespaceIptcCore, "CreatorContactInfo")
+	if !contact.IsStruct() || len(contact.Fields) != 2 {
+		t.Fatalf("CreatorContactInfo = %+v", contact)
+	}
+	if v, ok := contact.Field(namespaceIptcCore, "CiEmailWork").Text(); !ok || v != "jane@example.com" {
+		t.Errorf("CiEmailWork = %q, %v", v, ok)
+	}
+	if v := x.Property(tiff.NamespaceDC, "title").Items[0]; v.Lang != "fr" || v.Value != "Le pont" {
+		t.Errorf("title[0] = %+v", v)
+	}
+}
+
+func TestParseXMP(t *testing.T) {
+	x, err := tiff.ParseXMP([]byte(testXMP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXMP(t, x)
+
+	if _, err := tiff.ParseXMP([]byte("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"/>")); err == nil {
+		t.Errorf("no error without rdf:RDF")
+	}
+}
+
+func TestImage_SetXMP(t *testing.T) {
+	x, err := tiff.ParseXMP([]byte(testXMP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.SetDescription("A bridge over the Rhône")
+	x.SetText("http://example.com/ns/", "Custom", "<value>")
+
+	w := &writeSeeker{}
+	enc := tiff.NewEncoder(w)
+	im := enc.NewImage()
+	im.SetWidthHeight(2, 2)
+	im.SetPixelFormat(tiff.PhotometricBlackIsZero, 1, []int{8})
+	if err := im.SetXMP(x); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.EncodeImage(make([]uint8, 2*2)); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tiff.NewDecoder(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := d.Iter().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet, ok := ims[0].Tag[tiff.TagXMP].Value().Bytes()
+	if !ok || !bytes.HasSuffix(packet, []byte("<?xpacket end=\"w\"?>")) || !bytes.Contains(packet, bytes.Repeat([]byte(" "), 99)) {
+		t.Errorf("packet without padding:\n%s", packet)
+	}
+	got, err := ims[0].XMP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXMP(t, got)
+	if v, ok := got.Description(); !ok || v != "A bridge over the Rhône" {
+		t.Errorf("Description = %q, %v", v, ok)
+	}
+	if v, ok := got.Text("http://example.com/ns/", "Custom"); !ok || v != "<value>" {
+		t.Errorf("Custom = %q, %v", v, ok)
+	}
+	if got.Prefix[tiff.NamespaceDC] != "dc" || got.Prefix[namespaceIptcCore] != "Iptc4xmpCore" {
+		t.Errorf("Prefix = %v", got.Prefix)
+	}
+
+	im = enc.NewImage()
+	if v, err := im.XMP(); v != nil || err != nil {
+		t.Errorf("XMP of image without packet = %v, %v", v, err)
+	}
+	if err := im.SetXMP(nil); err == nil {
+		t.Errorf("no error for nil XMP")
+	}
+
+	// A new packet
+	x = &tiff.XMP{}
+	x.SetSubjects([]string{"bridge", "river"})
+	if err := im.SetXMP(x); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := im.XMP(); err != nil {
+		t.Fatal(err)
+	} else if v, ok := got.Subjects(); !ok || !reflect.DeepEqual(v, []string{"bridge", "river"}) {
+		t.Errorf("Subjects of new packet = %q, %v", v, ok)
+	}
+}